@@ -4,38 +4,76 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/matic-insurance/dns-tager/pkg"
 	"github.com/matic-insurance/dns-tager/provider"
 	"github.com/matic-insurance/dns-tager/provider/dnsimple"
+	"github.com/matic-insurance/dns-tager/provider/dynamodb"
 	"github.com/matic-insurance/dns-tager/registry"
 	"github.com/matic-insurance/dns-tager/source"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
 )
 
 func main() {
 	cfg := initConfig()
-	registry.Prefix = cfg.TXTPrefix
-	log.Infof("Running in '%s' mode", cfg.Mode)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go handleSigterm(cancel)
 
-	sourceEndpoints := getSourceEndpoints(ctx, cfg)
+	switch cfg.Command {
+	case pkg.OrphansCommand:
+		collectOrphans(ctx, cfg)
+	case pkg.ReleaseCommand:
+		releaseStaleRecords(ctx, cfg)
+	case pkg.ReassignCommand:
+		reassignRecords(ctx, cfg)
+	case pkg.InventoryCommand:
+		printInventory(ctx, cfg)
+	case pkg.FreezeCommand:
+		freezeRecords(ctx, cfg)
+	case pkg.UnfreezeCommand:
+		unfreezeRecords(ctx, cfg)
+	case pkg.ReadinessCommand:
+		checkReadiness(ctx, cfg)
+	case pkg.SnapshotCommand:
+		takeSnapshot(ctx, cfg)
+	case pkg.DiffCommand:
+		diffSnapshot(ctx, cfg)
+	case pkg.RestoreCommand:
+		restoreSnapshot(ctx, cfg)
+	default:
+		claim(ctx, cfg)
+	}
+}
+
+func claim(ctx context.Context, cfg *pkg.Config) {
+	log.Infof("Running in '%s' mode", cfg.Mode)
+	sourceEndpoints := getSourceEndpoints(ctx, cfg, cfg.KubeContext)
 	zones, dnsProvider := getZones(ctx, cfg)
+	ctx, zones, release := lockZones(ctx, cfg, dnsProvider, zones)
+	defer release()
 	selector := pkg.NewSelector(cfg, dnsProvider)
-	if cfg.Mode == "owner" {
+	if journal := openJournal(ctx, cfg); journal != nil {
+		selector.UseJournal(journal)
+	}
+	switch cfg.Mode {
+	case "owner":
 		configureNewOwner(ctx, cfg, selector, sourceEndpoints, zones)
-	} else {
+	case "adopt":
+		adoptHosts(ctx, cfg, selector, sourceEndpoints, zones)
+	default:
 		configureNewResource(ctx, cfg, selector, sourceEndpoints, zones)
 	}
 }
 
 func configureNewOwner(ctx context.Context, cfg *pkg.Config, selector *pkg.Selector, endpoints []*registry.Endpoint, zones []*registry.Zone) {
 	updatedRecords, err := selector.ClaimEndpointsOwnership(ctx, endpoints, zones)
-	if err != nil {
+	writeReport(cfg, selector.Report())
+	if err != nil && cfg.OnError == pkg.OnErrorAbort {
 		log.Fatalf("Owner updates aborted: %s", err)
 	}
 	if cfg.Apply {
@@ -43,11 +81,16 @@ func configureNewOwner(ctx context.Context, cfg *pkg.Config, selector *pkg.Selec
 	} else {
 		log.Infof("Finished updating registry records. Updated '%d' records in Dry Run mode", updatedRecords)
 	}
+	if err != nil {
+		log.Fatalf("Finished with failures:\n%s", err)
+	}
+	exitOnProblems(cfg, selector.Report())
 }
 
 func configureNewResource(ctx context.Context, cfg *pkg.Config, selector *pkg.Selector, endpoints []*registry.Endpoint, zones []*registry.Zone) {
 	updatedRecords, err := selector.ClaimEndpointsResource(ctx, endpoints, zones)
-	if err != nil {
+	writeReport(cfg, selector.Report())
+	if err != nil && cfg.OnError == pkg.OnErrorAbort {
 		log.Fatalf("Resource updates aborted: %s", err)
 	}
 	if cfg.Apply {
@@ -55,6 +98,289 @@ func configureNewResource(ctx context.Context, cfg *pkg.Config, selector *pkg.Se
 	} else {
 		log.Infof("Finished updating registry records. Updated '%d' records in Dry Run mode", updatedRecords)
 	}
+	if err != nil {
+		log.Fatalf("Finished with failures:\n%s", err)
+	}
+	exitOnProblems(cfg, selector.Report())
+}
+
+func adoptHosts(ctx context.Context, cfg *pkg.Config, selector *pkg.Selector, endpoints []*registry.Endpoint, zones []*registry.Zone) {
+	createdRecords, err := selector.AdoptEndpoints(ctx, endpoints, zones)
+	writeReport(cfg, selector.Report())
+	if err != nil && cfg.OnError == pkg.OnErrorAbort {
+		log.Fatalf("Adoption aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished creating registry records. Created '%d' records", createdRecords)
+	} else {
+		log.Infof("Finished creating registry records. Created '%d' records in Dry Run mode", createdRecords)
+	}
+	if err != nil {
+		log.Fatalf("Finished with failures:\n%s", err)
+	}
+	exitOnProblems(cfg, selector.Report())
+}
+
+func collectOrphans(ctx context.Context, cfg *pkg.Config) {
+	zones, dnsProvider := getZones(ctx, cfg)
+	if cfg.DeleteOrphans {
+		var release func()
+		ctx, zones, release = lockZones(ctx, cfg, dnsProvider, zones)
+		defer release()
+	}
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	orphans := selector.FindOrphanRecords(zones)
+	for _, orphan := range orphans {
+		log.WithFields(orphan.LogFields()).Info("Orphaned registry record")
+	}
+	log.Infof("Found '%d' orphaned registry records", len(orphans))
+	if !cfg.DeleteOrphans {
+		return
+	}
+
+	deletedRecords, err := selector.DeleteOrphanRecords(ctx, orphans)
+	if err != nil {
+		log.Fatalf("Orphans deletion aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished deleting registry records. Deleted '%d' records", deletedRecords)
+	} else {
+		log.Infof("Finished deleting registry records. Deleted '%d' records in Dry Run mode", deletedRecords)
+	}
+}
+
+func releaseStaleRecords(ctx context.Context, cfg *pkg.Config) {
+	sourceEndpoints := getSourceEndpoints(ctx, cfg, cfg.KubeContext)
+	zones, dnsProvider := getZones(ctx, cfg)
+	if cfg.ReleaseOwnerID != "" {
+		var release func()
+		ctx, zones, release = lockZones(ctx, cfg, dnsProvider, zones)
+		defer release()
+	}
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	staleRecords := selector.FindStaleRecords(sourceEndpoints, zones)
+	for _, staleRecord := range staleRecords {
+		log.WithFields(staleRecord.LogFields()).Info("Registry record not served by sources")
+	}
+	log.Infof("Found '%d' registry records owned by '%s' and not served by sources", len(staleRecords), cfg.CurrentOwnerID)
+	if cfg.ReleaseOwnerID == "" {
+		return
+	}
+
+	updatedRecords, err := selector.ReleaseRecords(ctx, staleRecords)
+	if err != nil {
+		log.Fatalf("Owner release aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished releasing registry records. Updated '%d' records", updatedRecords)
+	} else {
+		log.Infof("Finished releasing registry records. Updated '%d' records in Dry Run mode", updatedRecords)
+	}
+	reportSkippedRecords(cfg, selector)
+}
+
+func reassignRecords(ctx context.Context, cfg *pkg.Config) {
+	zones, dnsProvider := getZones(ctx, cfg)
+	ctx, zones, release := lockZones(ctx, cfg, dnsProvider, zones)
+	defer release()
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	selectedRecords := selector.FindReassignRecords(zones)
+	for _, selectedRecord := range selectedRecords {
+		log.WithFields(selectedRecord.LogFields()).Info("Registry record selected for reassignment")
+	}
+	log.Infof("Found '%d' registry records owned by '%s'", len(selectedRecords), cfg.FromOwnerID)
+
+	updatedRecords, err := selector.ReassignRecords(ctx, selectedRecords)
+	if err != nil {
+		log.Fatalf("Owner reassignment aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished reassigning registry records. Updated '%d' records", updatedRecords)
+	} else {
+		log.Infof("Finished reassigning registry records. Updated '%d' records in Dry Run mode", updatedRecords)
+	}
+	reportSkippedRecords(cfg, selector)
+}
+
+func freezeRecords(ctx context.Context, cfg *pkg.Config) {
+	zones, dnsProvider := getZones(ctx, cfg)
+	ctx, zones, release := lockZones(ctx, cfg, dnsProvider, zones)
+	defer release()
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	selectedRecords := selector.FindFreezeRecords(zones)
+	for _, selectedRecord := range selectedRecords {
+		log.WithFields(selectedRecord.LogFields()).Info("Registry record selected for freeze")
+	}
+
+	updatedRecords, err := selector.FreezeRecords(ctx, selectedRecords)
+	if err != nil {
+		log.Fatalf("Freeze aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished freezing registry records. Updated '%d' records", updatedRecords)
+	} else {
+		log.Infof("Finished freezing registry records. Updated '%d' records in Dry Run mode", updatedRecords)
+	}
+	reportSkippedRecords(cfg, selector)
+}
+
+func unfreezeRecords(ctx context.Context, cfg *pkg.Config) {
+	zones, dnsProvider := getZones(ctx, cfg)
+	ctx, zones, release := lockZones(ctx, cfg, dnsProvider, zones)
+	defer release()
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	selectedRecords := selector.FindUnfreezeRecords(zones)
+	for _, selectedRecord := range selectedRecords {
+		log.WithFields(selectedRecord.LogFields()).Info("Registry record selected for unfreeze")
+	}
+
+	updatedRecords, err := selector.UnfreezeRecords(ctx, selectedRecords)
+	if err != nil {
+		log.Fatalf("Unfreeze aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished unfreezing registry records. Updated '%d' records", updatedRecords)
+	} else {
+		log.Infof("Finished unfreezing registry records. Updated '%d' records in Dry Run mode", updatedRecords)
+	}
+	reportSkippedRecords(cfg, selector)
+}
+
+func printInventory(ctx context.Context, cfg *pkg.Config) {
+	zones, _ := getZones(ctx, cfg)
+	if err := pkg.NewInventory(zones).Write(os.Stdout, cfg.Output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeReport logs run summary and writes claim results to stdout
+func writeReport(cfg *pkg.Config, report *pkg.Report) {
+	report.Log()
+	if err := report.WriteResults(os.Stdout, cfg.ReportFormat); err != nil {
+		log.Error(err)
+	}
+}
+
+// exitOnProblems exits with code 2 when requested and any endpoint or registry record was not claimed
+func exitOnProblems(cfg *pkg.Config, report *pkg.Report) {
+	if cfg.FailOnProblems && report.HasProblems() {
+		log.Errorf("Finished with problems: %v", report.Outcomes())
+		log.Exit(2)
+	}
+}
+
+// reportSkippedRecords lists registry records rewritten since zones were read, with --fail-on-problems
+// the run exits with code 2 when any record was skipped
+func reportSkippedRecords(cfg *pkg.Config, selector *pkg.Selector) {
+	skipped := selector.SkippedRecords()
+	if len(skipped) == 0 {
+		return
+	}
+	for _, record := range skipped {
+		log.WithFields(record.LogFields()).Warn("Registry record skipped, it was rewritten since zones were read")
+	}
+	log.Warnf("Skipped '%d' registry records rewritten since zones were read", len(skipped))
+	if cfg.FailOnProblems {
+		log.Errorf("Finished with problems: '%d' records %s", len(skipped), pkg.OutcomeChanged)
+		log.Exit(2)
+	}
+}
+
+func checkReadiness(ctx context.Context, cfg *pkg.Config) {
+	oldEndpoints := getSourceEndpoints(ctx, cfg, cfg.OldKubeContext)
+	newEndpoints := getSourceEndpoints(ctx, cfg, cfg.NewKubeContext)
+	zones, dnsProvider := getZones(ctx, cfg)
+	readiness := pkg.NewSelector(cfg, dnsProvider).CheckReadiness(oldEndpoints, newEndpoints, zones)
+	if err := readiness.Write(os.Stdout, cfg.Output); err != nil {
+		log.Fatal(err)
+	}
+	if !readiness.Ready() {
+		log.Warnf("New cluster is not ready. '%d' hosts without source, '%d' hosts with resource mismatch", len(readiness.Missing), len(readiness.ResourceMismatches))
+	}
+}
+
+func takeSnapshot(ctx context.Context, cfg *pkg.Config) {
+	zones, _ := getZones(ctx, cfg)
+	snapshot := pkg.NewSnapshot(zones, cfg, time.Now())
+	if err := snapshot.WriteFile(cfg.SnapshotFile); err != nil {
+		log.Fatalf("Failed to write snapshot: %s", err)
+	}
+	log.Infof("Finished snapshot. Wrote '%d' registry records to '%s'", len(snapshot.Records), cfg.SnapshotFile)
+}
+
+func diffSnapshot(ctx context.Context, cfg *pkg.Config) {
+	base := readSnapshot(cfg, cfg.SnapshotFile)
+	var target *pkg.Snapshot
+	if cfg.AgainstSnapshotFile != "" {
+		target = readSnapshot(cfg, cfg.AgainstSnapshotFile)
+	} else {
+		zones, _ := getZones(ctx, cfg)
+		target = pkg.NewSnapshot(zones, cfg, time.Now())
+	}
+	diff := pkg.DiffSnapshots(base, target)
+	if err := diff.Write(os.Stdout, cfg.Output); err != nil {
+		log.Fatal(err)
+	}
+	// registry records differ, so scripts can check rollback or migration result by exit code
+	if !diff.IsEmpty() {
+		log.Exit(2)
+	}
+}
+
+func restoreSnapshot(ctx context.Context, cfg *pkg.Config) {
+	snapshot := readSnapshot(cfg, cfg.SnapshotFile)
+	zones, dnsProvider := getZones(ctx, cfg)
+	ctx, zones, release := lockZones(ctx, cfg, dnsProvider, zones)
+	defer release()
+	selector := pkg.NewSelector(cfg, dnsProvider)
+	changedRecords, err := selector.RestoreSnapshot(ctx, snapshot, zones)
+	if err != nil {
+		log.Fatalf("Restore aborted: %s", err)
+	}
+	if cfg.Apply {
+		log.Infof("Finished restoring registry records. Updated '%d' records", changedRecords)
+	} else {
+		log.Infof("Finished restoring registry records. Updated '%d' records in Dry Run mode", changedRecords)
+	}
+	reportSkippedRecords(cfg, selector)
+}
+
+// readSnapshot loads snapshot taken with the same registry as configured
+func readSnapshot(cfg *pkg.Config, path string) *pkg.Snapshot {
+	snapshot, err := pkg.ReadSnapshot(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if snapshot.Registry != cfg.Registry {
+		log.Fatalf("Snapshot '%s' was taken from '%s' registry, but '%s' registry is configured", path, snapshot.Registry, cfg.Registry)
+	}
+	return snapshot
+}
+
+// lockZones acquires run lock of the zones with --lock and returns zones read under the lock, changes must be
+// planned from them. Returned release is also called on fatal errors, so the lease of the failed run doesn't block
+// the next run until it expires
+func lockZones(ctx context.Context, cfg *pkg.Config, dnsProvider provider.Provider, zones []*registry.Zone) (context.Context, []*registry.Zone, func()) {
+	if !cfg.Lock {
+		return ctx, zones, func() {}
+	}
+	lock := pkg.NewLock(cfg, dnsProvider)
+	lockedCtx, lockedZones, err := lock.Acquire(ctx, zones)
+	if err != nil {
+		log.Fatalf("Failed to lock zones: %s", err)
+	}
+	log.Infof("Locked '%d' zones by run '%s'", len(zones), lock.RunID())
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if err := lock.Release(context.Background()); err != nil {
+				log.Errorf("Failed to unlock zones: %s", err)
+			}
+		})
+	}
+	log.RegisterExitHandler(release)
+	return lockedCtx, lockedZones, release
 }
 
 func initConfig() *pkg.Config {
@@ -84,7 +410,7 @@ func handleSigterm(cancel func()) {
 	cancel()
 }
 
-func getSourceEndpoints(ctx context.Context, cfg *pkg.Config) []*registry.Endpoint {
+func getSourceEndpoints(ctx context.Context, cfg *pkg.Config, kubeContext string) []*registry.Endpoint {
 	// Create a source.Config from the flags passed by the user.
 	sourceCfg := &source.Config{
 		Namespace:      cfg.Namespace,
@@ -95,6 +421,7 @@ func getSourceEndpoints(ctx context.Context, cfg *pkg.Config) []*registry.Endpoi
 	}
 	sources, err := source.ByNames(ctx, &source.SingletonClientGenerator{
 		KubeConfig:   cfg.KubeConfig,
+		KubeContext:  kubeContext,
 		APIServerURL: cfg.APIServerURL,
 		// If update events are enabled, disable timeout.
 		RequestTimeout: func() time.Duration {
@@ -106,7 +433,7 @@ func getSourceEndpoints(ctx context.Context, cfg *pkg.Config) []*registry.Endpoi
 		log.Fatal(err)
 	}
 
-	log.Info("Fetching source endpoints")
+	log.WithField("context", kubeContext).Info("Fetching source endpoints")
 
 	var endpoints []*registry.Endpoint
 	for _, endpointsSource := range sources {
@@ -121,14 +448,50 @@ func getSourceEndpoints(ctx context.Context, cfg *pkg.Config) []*registry.Endpoi
 	return endpoints
 }
 
+// openJournal starts new journal or loads journal of the resumed run, nil when journal is not configured
+func openJournal(ctx context.Context, cfg *pkg.Config) *pkg.Journal {
+	location := cfg.Journal
+	if cfg.Resume != "" {
+		location = cfg.Resume
+	}
+	if location == "" {
+		return nil
+	}
+	store, err := pkg.NewJournalStore(location, func() (kubernetes.Interface, error) {
+		return source.NewKubeClient(cfg.KubeConfig, cfg.KubeContext, cfg.APIServerURL, cfg.RequestTimeout)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Resume == "" {
+		log.Infof("Writing journal to %s", store)
+		return pkg.NewJournal(store, cfg)
+	}
+
+	journal, err := pkg.ResumeJournal(ctx, store, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("Resuming run from %s with '%d' planned changes", store, len(journal.Entries))
+	return journal
+}
+
 func getZones(ctx context.Context, cfg *pkg.Config) ([]*registry.Zone, provider.Provider) {
 	dnsProvider, err := dnsimple.NewDnsimpleProvider(cfg, cfg.DNSZones)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cfg.Registry == "dynamodb" {
+		ownership, err := dynamodb.NewDynamoDBRegistry(ctx, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dnsProvider = provider.WithRegistry(dnsProvider, ownership)
+	}
+	dnsProvider = provider.WithRateLimit(dnsProvider, cfg.ChangesRateLimit())
 
 	log.Info("Fetching registry records")
-	zones, err := dnsProvider.ReadZones(ctx)
+	zones, err := dnsProvider.ReadZones(ctx, cfg.Matcher())
 	if err != nil {
 		log.Fatal(err)
 	}
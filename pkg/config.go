@@ -2,20 +2,83 @@ package pkg
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kingpin"
+	"github.com/matic-insurance/dns-tager/registry"
 	"github.com/sirupsen/logrus"
 )
 
 var Version = "unknown"
 
+// Commands of dns-tagger
+const (
+	// ClaimCommand updates registry records of the hosts served by configured sources
+	ClaimCommand = "claim"
+	// OrphansCommand lists and deletes registry records without hosts
+	OrphansCommand = "orphans"
+	// ReleaseCommand reports and releases registry records of current owner that are no longer served by sources
+	ReleaseCommand = "release"
+	// ReassignCommand changes owner of registry records selected on DNS side only
+	ReassignCommand = "reassign"
+	// FreezeCommand parks registry records with parking owner id
+	FreezeCommand = "freeze"
+	// UnfreezeCommand restores original owners of parked registry records
+	UnfreezeCommand = "unfreeze"
+	// InventoryCommand prints hosts of the zones with their owners
+	InventoryCommand = "inventory"
+	// ReadinessCommand compares hosts served by old and new clusters before migration
+	ReadinessCommand = "readiness"
+	// SnapshotCommand writes registry records of the zones to the snapshot file
+	SnapshotCommand = "snapshot"
+	// DiffCommand compares snapshot with another snapshot or with live DNS
+	DiffCommand = "diff"
+	// RestoreCommand rewrites registry records back to the snapshot
+	RestoreCommand = "restore"
+)
+
+// Owner policies for resource mode
+const (
+	// ResourceOwnerCurrent updates only records owned by current owner id
+	ResourceOwnerCurrent = "current"
+	// ResourceOwnerAllowed updates records owned by current or previous owner ids
+	ResourceOwnerAllowed = "allowed"
+	// ResourceOwnerAny updates records regardless of the owner
+	ResourceOwnerAny = "any"
+)
+
+// Strategies for hosts with several registry records
+const (
+	// DuplicateRecordsAll updates every registry record of the host
+	DuplicateRecordsAll = "all"
+	// DuplicateRecordsCanonical updates only canonical registry record of the host
+	DuplicateRecordsCanonical = "canonical"
+	// DuplicateRecordsDelete deletes redundant registry records with the same name and updates the rest
+	DuplicateRecordsDelete = "delete"
+)
+
+// Rate limits of registry record changes
+const (
+	// RateLimitDefault selects rate limit of the registry when --rate-limit is not set
+	RateLimitDefault = -1
+	// DNSimpleRateLimit stays within DNSimple limit of 2400 requests per hour, registry record update reads
+	// the record first and takes two requests
+	DNSimpleRateLimit = 0.3
+)
+
 type Config struct {
+	Command        string
 	Mode           string
 	AccountId      string
 	APIServerURL   string
 	KubeConfig     string
+	KubeContext    string
 	RequestTimeout time.Duration
 	Sources        []string
 	Namespace      string
@@ -27,18 +90,69 @@ type Config struct {
 	LogFormat string
 	LogLevel  string
 
-	Apply            bool
-	CurrentOwnerID   string
-	PreviousOwnerIDs []string
-	DNSZones         []string
-	TXTPrefix        string
+	Apply                  bool
+	ReportFormat           string
+	FailOnProblems         bool
+	OnError                string
+	Concurrency            int
+	RateLimit              float64
+	Journal                string
+	Resume                 string
+	Lock                   bool
+	LockName               string
+	LockTTL                time.Duration
+	LockHolder             string
+	CurrentOwnerID         string
+	PreviousOwnerIDs       []string
+	ResourceOwnerPolicy    string
+	DuplicateRecords       string
+	DNSZones               []string
+	TXTPrefix              string
+	TXTSuffix              string
+	TXTWildcardReplacement string
+	ManagedRecordTypes     []string
+	SourcePriority         []string
+	HostOverrides          map[string]string
+
+	TXTEncryptEnabled    bool
+	TXTEncryptAESKey     string `secure:"yes"`
+	TXTEncryptAESKeyFile string
+
+	Registry         string
+	DynamoDBTable    string
+	DynamoDBRegion   string
+	DynamoDBEndpoint string
+
+	OrphanOwnerIDs []string
+	DeleteOrphans  bool
+
+	ReleaseOwnerID string
+
+	FromOwnerID     string
+	ToOwnerID       string
+	HostRegex       *regexp.Regexp
+	ResourcePattern string
+
+	ParkingOwnerID string
+	FreezeOwnerIDs []string
+
+	OldKubeContext string
+	NewKubeContext string
+
+	SnapshotFile        string
+	AgainstSnapshotFile string
+	AllowDecrypted      bool
+
+	Output string
 }
 
 var defaultConfig = &Config{
+	Command:        ClaimCommand,
 	Mode:           "owner",
 	AccountId:      "",
 	APIServerURL:   "",
 	KubeConfig:     "",
+	KubeContext:    "",
 	RequestTimeout: time.Second * 30,
 	Sources:        nil,
 	Namespace:      "",
@@ -47,9 +161,58 @@ var defaultConfig = &Config{
 	LogFormat:      "text",
 	LogLevel:       logrus.InfoLevel.String(),
 
-	Apply:     false,
-	DNSZones:  []string{},
-	TXTPrefix: "edns-",
+	Apply:                  false,
+	ReportFormat:           OutputTable,
+	FailOnProblems:         false,
+	OnError:                OnErrorAbort,
+	Concurrency:            1,
+	RateLimit:              RateLimitDefault,
+	Journal:                "",
+	Resume:                 "",
+	Lock:                   false,
+	LockName:               "dns-tagger-lock",
+	LockTTL:                5 * time.Minute,
+	LockHolder:             "",
+	ResourceOwnerPolicy:    ResourceOwnerCurrent,
+	DuplicateRecords:       DuplicateRecordsAll,
+	DNSZones:               []string{},
+	TXTPrefix:              "edns-",
+	TXTSuffix:              "",
+	TXTWildcardReplacement: "",
+	ManagedRecordTypes:     registry.DefaultRecordTypes,
+	SourcePriority:         nil,
+	HostOverrides:          nil,
+
+	TXTEncryptEnabled:    false,
+	TXTEncryptAESKey:     "",
+	TXTEncryptAESKeyFile: "",
+
+	Registry:         "txt",
+	DynamoDBTable:    "external-dns",
+	DynamoDBRegion:   "",
+	DynamoDBEndpoint: "",
+
+	OrphanOwnerIDs: nil,
+	DeleteOrphans:  false,
+
+	ReleaseOwnerID: "",
+
+	FromOwnerID:     "",
+	ToOwnerID:       "",
+	HostRegex:       nil,
+	ResourcePattern: "",
+
+	ParkingOwnerID: "dns-tagger-frozen",
+	FreezeOwnerIDs: nil,
+
+	OldKubeContext: "",
+	NewKubeContext: "",
+
+	SnapshotFile:        "",
+	AgainstSnapshotFile: "",
+	AllowDecrypted:      false,
+
+	Output: OutputTable,
 }
 
 func NewConfig() *Config {
@@ -77,6 +240,47 @@ func (cfg *Config) String() string {
 	return fmt.Sprintf("%+v", temp)
 }
 
+// Encryptor returns registry records encryptor from the configured AES key or nil when encryption is disabled
+func (cfg *Config) Encryptor() (*registry.Encryptor, error) {
+	if !cfg.TXTEncryptEnabled {
+		return nil, nil
+	}
+	key := cfg.TXTEncryptAESKey
+	if cfg.TXTEncryptAESKeyFile != "" {
+		content, err := os.ReadFile(cfg.TXTEncryptAESKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read AES key file: %w", err)
+		}
+		key = strings.TrimSpace(string(content))
+	}
+	if key == "" {
+		return nil, fmt.Errorf("--txt-encrypt-aes-key or --txt-encrypt-aes-key-file is required when encryption is enabled")
+	}
+	return registry.NewEncryptor(key)
+}
+
+// ChangesRateLimit returns --rate-limit or the default of the registry when it is not set. Dry runs don't send
+// changes and are not limited by default
+func (cfg *Config) ChangesRateLimit() float64 {
+	switch {
+	case cfg.RateLimit != RateLimitDefault:
+		return cfg.RateLimit
+	case !cfg.Apply || cfg.Registry == "dynamodb":
+		return 0
+	default:
+		return DNSimpleRateLimit
+	}
+}
+
+// Matcher returns registry records matcher configured with TXT affixes and managed record types
+func (cfg *Config) Matcher() *registry.Matcher {
+	matcher := registry.NewMatcher(cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTWildcardReplacement)
+	if len(cfg.ManagedRecordTypes) > 0 {
+		matcher.RecordTypes = cfg.ManagedRecordTypes
+	}
+	return matcher
+}
+
 // allLogLevelsAsStrings returns all logrus levels as a list of strings
 func allLogLevelsAsStrings() []string {
 	var levels []string
@@ -91,41 +295,229 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app := kingpin.New("dns-tagger", "DNS Tagger Allows to change External DNS Records owner between clusters.\n\nNote that all flags may be replaced with env vars - `--flag` -> `EXTERNAL_DNS_DIALER_FLAG=1` or `--flag value` -> `EXTERNAL_DNS_DIALER_FLAG=value`")
 	app.Version(Version)
 	app.DefaultEnvars()
+	cfg.HostOverrides = make(map[string]string)
 
 	// dns-tagger mode
-	app.Flag("mode", "Determines the operation of the dns-tagger (default: owner, options: owner, resource)").Default(defaultConfig.Mode).EnumVar(&cfg.Mode, "owner", "resource")
+	app.Flag("mode", "Determines the operation of the dns-tagger (default: owner, options: owner, resource, adopt)").Default(defaultConfig.Mode).EnumVar(&cfg.Mode, "owner", "resource", "adopt")
 
 	app.Flag("account-id", "DNSimple account id (default: auto-detect)").Default(defaultConfig.AccountId).StringVar(&cfg.AccountId)
 
 	// Flags related to Kubernetes
 	app.Flag("server", "The Kubernetes API server to connect to (default: auto-detect)").Default(defaultConfig.APIServerURL).StringVar(&cfg.APIServerURL)
 	app.Flag("kubeconfig", "Retrieve target cluster configuration from a Kubernetes configuration file (default: auto-detect)").Default(defaultConfig.KubeConfig).StringVar(&cfg.KubeConfig)
+	app.Flag("context", "Kubernetes context of the configuration file to query sources from (default: current context)").Default(defaultConfig.KubeContext).StringVar(&cfg.KubeContext)
 	app.Flag("request-timeout", "Request timeout when calling Kubernetes APIs. 0s means no timeout").Default(defaultConfig.RequestTimeout.String()).DurationVar(&cfg.RequestTimeout)
 
 	// Flags related to processing source
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: ingress, istio-virtualservice").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "ingress", "istio-virtualservice")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required by claim, options: ingress, istio-virtualservice").PlaceHolder("source").EnumsVar(&cfg.Sources, "ingress", "istio-virtualservice")
 	app.Flag("namespace", "Limit resources queried for endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
+	app.Flag("source-priority", "Sources preferred when several sources claim the same host, highest priority first; comma separated or specified multiple times (default: conflicting hosts are skipped, options: ingress, istio-virtualservice)").PlaceHolder("source").StringsVar(&cfg.SourcePriority)
+	app.Flag("host-override", "Source or resource that wins the conflict for the host, e.g. api.example.com=istio-virtualservice or api.example.com=ingress/default/api; specify multiple times for multiple hosts").PlaceHolder("host=source").StringMapVar(&cfg.HostOverrides)
 	app.Flag("label", "Label selector to filter sources (ingress and istio-virtualservice) by label; may be specified multiple times. Format: \"key:value\" or \"key=value\". If multiple labels are provided, they are combined with OR semantics. (default: no filter)").PlaceHolder("label").StringsVar(&cfg.Labels)
 
 	// Flags related to operations
 	app.Flag("apply", "When enabled, executes dns changes (default: disabled)").BoolVar(&cfg.Apply)
-	// TODO current-owner-id is optional in "resource" mode
-	app.Flag("current-owner-id", "What owner id to set when records changing ownership").Required().StringVar(&cfg.CurrentOwnerID)
-	// TODO previous-owner-id is optional in "resource" mode
-	app.Flag("previous-owner-id", "What previous owner ids are allowed for migration").Required().PlaceHolder("previous-owner-id").StringsVar(&cfg.PreviousOwnerIDs)
+	app.Flag("report-format", "Format of the claim results written to stdout at the end of the run (default: table, options: table, json, markdown)").Default(defaultConfig.ReportFormat).EnumVar(&cfg.ReportFormat, OutputTable, OutputJSON, OutputMarkdown)
+	app.Flag("fail-on-problems", "When enabled, exits with code 2 if any endpoint or registry record was not claimed because of owner, zone, host, registry or source conflict problems, or was skipped because it was rewritten during the run (default: disabled)").BoolVar(&cfg.FailOnProblems)
+	app.Flag("on-error", "What to do when DNS provider fails to change registry record (default: abort, options: abort - stop on the first error, continue - process remaining records and report all failures at the end)").Default(defaultConfig.OnError).EnumVar(&cfg.OnError, OnErrorAbort, OnErrorContinue)
+	app.Flag("concurrency", "Number of endpoints claimed in parallel (default: 1)").Default(strconv.Itoa(defaultConfig.Concurrency)).IntVar(&cfg.Concurrency)
+	app.Flag("rate-limit", "Maximum registry record changes per second sent to the DNS provider or registry, shared by all workers, 0 - unlimited (default: 0.3 for TXT registry in DNSimple, unlimited for DynamoDB registry)").Default(strconv.FormatFloat(defaultConfig.RateLimit, 'f', -1, 64)).Float64Var(&cfg.RateLimit)
+	app.Flag("journal", "Write every planned registry record change and whether it was applied to the file or to the ConfigMap as configmap:namespace/name (default: no journal)").Default(defaultConfig.Journal).StringVar(&cfg.Journal)
+	app.Flag("resume", "Resume interrupted run from the journal file or configmap:namespace/name, applied changes are skipped and the journal is updated (default: new run)").Default(defaultConfig.Resume).StringVar(&cfg.Resume)
+	app.Flag("lock", "When enabled, writes lease TXT record into every zone before changing registry records and refuses to run while another run holds the lease (default: disabled)").BoolVar(&cfg.Lock)
+	app.Flag("lock-name", "Name of the lease record relative to the zone (default: dns-tagger-lock)").Default(defaultConfig.LockName).StringVar(&cfg.LockName)
+	app.Flag("lock-ttl", "Lease duration, the lease is renewed every third of it while the run is in progress (default: 5m)").Default(defaultConfig.LockTTL.String()).DurationVar(&cfg.LockTTL)
+	app.Flag("lock-holder", "Holder written to the lease record (default: host name)").Default(defaultConfig.LockHolder).StringVar(&cfg.LockHolder)
+	app.Flag("current-owner-id", "What owner id to set when records changing ownership (required in owner mode)").StringVar(&cfg.CurrentOwnerID)
+	app.Flag("previous-owner-id", "What previous owner ids are allowed for migration (required in owner mode)").PlaceHolder("previous-owner-id").StringsVar(&cfg.PreviousOwnerIDs)
+	app.Flag("resource-owner-policy", "What owners of records are allowed to be updated in resource mode (default: current, options: current - current owner id, allowed - current or previous owner ids, any - all owners)").Default(defaultConfig.ResourceOwnerPolicy).EnumVar(&cfg.ResourceOwnerPolicy, ResourceOwnerCurrent, ResourceOwnerAllowed, ResourceOwnerAny)
+	app.Flag("duplicate-records", "How to handle hosts with several registry records (default: all, options: all - update every record, canonical - update only the record External DNS relies on, delete - delete redundant records with the same name)").Default(defaultConfig.DuplicateRecords).EnumVar(&cfg.DuplicateRecords, DuplicateRecordsAll, DuplicateRecordsCanonical, DuplicateRecordsDelete)
 	app.Flag("dns-zone", "What dns zone should be considered").Required().PlaceHolder("dns-zone").Default(cfg.DNSZones...).StringsVar(&cfg.DNSZones)
+	app.Flag("managed-record-types", "Record types of hosts managed by External DNS; specify multiple times for multiple types (default: A, AAAA, CNAME, options: A, AAAA, CNAME, NS, MX, SRV, CAA, ALIAS)").Default(defaultConfig.ManagedRecordTypes...).EnumsVar(&cfg.ManagedRecordTypes, "A", "AAAA", "CNAME", "NS", "MX", "SRV", "CAA", "ALIAS")
 
 	// TXT record configuration
-	app.Flag("txt-prefix", "Prefix for TXT records").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
+	app.Flag("txt-prefix", "Prefix for TXT records, may contain dots and %{record_type} template (default: edns-)").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
+	app.Flag("txt-suffix", "Suffix for TXT records, may contain dots and %{record_type} template (default: empty)").Default(defaultConfig.TXTSuffix).StringVar(&cfg.TXTSuffix)
+	app.Flag("txt-wildcard-replacement", "Replacement of the wildcard label in registry records names (default: no replacement)").Default(defaultConfig.TXTWildcardReplacement).StringVar(&cfg.TXTWildcardReplacement)
+
+	app.Flag("txt-encrypt-enabled", "When enabled, reads and writes registry records encrypted with AES-GCM key (default: disabled)").BoolVar(&cfg.TXTEncryptEnabled)
+	app.Flag("txt-encrypt-aes-key", "AES key (32 bytes, plain text or base64) used to encrypt registry records").Default(defaultConfig.TXTEncryptAESKey).StringVar(&cfg.TXTEncryptAESKey)
+	app.Flag("txt-encrypt-aes-key-file", "File containing AES key used to encrypt registry records").Default(defaultConfig.TXTEncryptAESKeyFile).StringVar(&cfg.TXTEncryptAESKeyFile)
+
+	// Registry backend configuration
+	app.Flag("registry", "The registry implementation used by External DNS to keep ownership (default: txt, options: txt, dynamodb)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "dynamodb")
+	app.Flag("dynamodb-table", "When using the DynamoDB registry, the name of the DynamoDB table (default: external-dns)").Default(defaultConfig.DynamoDBTable).StringVar(&cfg.DynamoDBTable)
+	app.Flag("dynamodb-region", "When using the DynamoDB registry, the AWS region of the DynamoDB table (default: auto-detect)").Default(defaultConfig.DynamoDBRegion).StringVar(&cfg.DynamoDBRegion)
+	app.Flag("dynamodb-endpoint", "When using the DynamoDB registry, custom endpoint of DynamoDB compatible service (default: AWS endpoint)").Default(defaultConfig.DynamoDBEndpoint).StringVar(&cfg.DynamoDBEndpoint)
 
 	// Miscellaneous flags
 	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
 	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal)").Default(defaultConfig.LogLevel).EnumVar(&cfg.LogLevel, allLogLevelsAsStrings()...)
 
-	_, err := app.Parse(args)
+	// Commands
+	app.Command(ClaimCommand, "Update registry records of the hosts served by configured sources according to --mode").Default()
+
+	orphans := app.Command(OrphansCommand, "List registry records without hosts, delete them with --delete")
+	orphans.Flag("owner-id", "List only registry records of the owner ids; specify multiple times for multiple owners (default: all owners, required with --delete)").PlaceHolder("owner-id").StringsVar(&cfg.OrphanOwnerIDs)
+	orphans.Flag("delete", "When enabled, deletes listed registry records, changes are made only with --apply (default: disabled)").BoolVar(&cfg.DeleteOrphans)
+
+	release := app.Command(ReleaseCommand, "List registry records owned by current owner id whose hosts are not served by configured sources")
+	release.Flag("release-owner-id", "Owner id to hand listed registry records over to, changes are made only with --apply (default: only list records)").Default(defaultConfig.ReleaseOwnerID).StringVar(&cfg.ReleaseOwnerID)
+
+	reassign := app.Command(ReassignCommand, "Change owner id of registry records selected by owner, host and resource without Kubernetes access")
+	reassign.Flag("from-owner-id", "Owner id of registry records to reassign (required)").Default(defaultConfig.FromOwnerID).StringVar(&cfg.FromOwnerID)
+	reassign.Flag("to-owner-id", "Owner id to set on selected registry records (required)").Default(defaultConfig.ToOwnerID).StringVar(&cfg.ToOwnerID)
+	reassign.Flag("host-regex", "Reassign only registry records of hosts matching the regular expression (default: all hosts, including records without hosts)").RegexpVar(&cfg.HostRegex)
+	reassign.Flag("resource-pattern", "Reassign only registry records with resource matching the shell pattern, e.g. ingress/production/* (default: all resources)").Default(defaultConfig.ResourcePattern).StringVar(&cfg.ResourcePattern)
+
+	for _, command := range []*kingpin.CmdClause{
+		app.Command(FreezeCommand, "Move selected registry records to parking owner id, so External DNS instances stop changing them"),
+		app.Command(UnfreezeCommand, "Restore original owners of registry records parked by freeze"),
+	} {
+		command.Flag("parking-owner-id", "Owner id that is not used by any External DNS instance (default: dns-tagger-frozen)").Default(defaultConfig.ParkingOwnerID).StringVar(&cfg.ParkingOwnerID)
+		command.Flag("owner-id", "Select only registry records of the original owner ids; specify multiple times for multiple owners (default: all owners)").PlaceHolder("owner-id").StringsVar(&cfg.FreezeOwnerIDs)
+		command.Flag("host-regex", "Select only registry records of hosts matching the regular expression (default: all hosts, including records without hosts)").RegexpVar(&cfg.HostRegex)
+		command.Flag("resource-pattern", "Select only registry records with resource matching the shell pattern (default: all resources)").Default(defaultConfig.ResourcePattern).StringVar(&cfg.ResourcePattern)
+	}
+
+	inventory := app.Command(InventoryCommand, "Print hosts of the zones with record type, values, registry records, owner and resource")
+	inventory.Flag("output", "Output format (default: table, options: table, json, csv)").Default(defaultConfig.Output).EnumVar(&cfg.Output, OutputTable, OutputJSON, OutputCSV)
+
+	readiness := app.Command(ReadinessCommand, "Compare hosts owned by previous owner ids with sources of the old and new clusters")
+	readiness.Flag("old-context", "Kubernetes context of the old cluster in the configuration file (required)").Default(defaultConfig.OldKubeContext).StringVar(&cfg.OldKubeContext)
+	readiness.Flag("new-context", "Kubernetes context of the new cluster in the configuration file (required)").Default(defaultConfig.NewKubeContext).StringVar(&cfg.NewKubeContext)
+	readiness.Flag("output", "Output format (default: table, options: table, json)").Default(defaultConfig.Output).EnumVar(&cfg.Output, OutputTable, OutputJSON)
+
+	snapshot := app.Command(SnapshotCommand, "Write all registry records of the zones with provider record ids to the snapshot file")
+	snapshot.Flag("file", "Snapshot file to write (required)").Default(defaultConfig.SnapshotFile).StringVar(&cfg.SnapshotFile)
+	snapshot.Flag("allow-decrypted", "Allow snapshot of encrypted TXT registry, records are written to the file decrypted (default: disabled)").BoolVar(&cfg.AllowDecrypted)
+
+	diff := app.Command(DiffCommand, "Compare registry records of the snapshot with another snapshot or with live DNS, exits with code 2 when they differ")
+	diff.Flag("file", "Base snapshot file (required)").Default(defaultConfig.SnapshotFile).StringVar(&cfg.SnapshotFile)
+	diff.Flag("against", "Snapshot file to compare the base snapshot with (default: live DNS)").Default(defaultConfig.AgainstSnapshotFile).StringVar(&cfg.AgainstSnapshotFile)
+	diff.Flag("output", "Output format (default: table, options: table, json)").Default(defaultConfig.Output).EnumVar(&cfg.Output, OutputTable, OutputJSON)
+
+	restore := app.Command(RestoreCommand, "Rewrite registry records of the zones back to the snapshot, changes are made only with --apply")
+	restore.Flag("file", "Snapshot file to restore (required)").Default(defaultConfig.SnapshotFile).StringVar(&cfg.SnapshotFile)
+
+	command, err := app.Parse(args)
 	if err != nil {
 		return err
 	}
+	cfg.Command = command
+	cfg.SourcePriority = splitList(cfg.SourcePriority)
+
+	return cfg.validate()
+}
+
+// validate checks flags that are required depending on the command and mode
+func (cfg *Config) validate() error {
+	if cfg.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if cfg.RateLimit < 0 && cfg.RateLimit != RateLimitDefault {
+		return fmt.Errorf("--rate-limit must not be negative")
+	}
+	if cfg.Lock && (cfg.LockName == "" || cfg.LockTTL < time.Second) {
+		return fmt.Errorf("--lock requires --lock-name and --lock-ttl of at least 1s")
+	}
+
+	switch cfg.Command {
+	case OrphansCommand:
+		if cfg.DeleteOrphans && len(cfg.OrphanOwnerIDs) == 0 {
+			return fmt.Errorf("--owner-id is required to delete orphaned registry records")
+		}
+		return nil
+	case ReleaseCommand:
+		if len(cfg.Sources) == 0 {
+			return fmt.Errorf("--source is required by %s command", ReleaseCommand)
+		}
+		if cfg.CurrentOwnerID == "" {
+			return fmt.Errorf("--current-owner-id is required by %s command", ReleaseCommand)
+		}
+		if cfg.ReleaseOwnerID == cfg.CurrentOwnerID {
+			return fmt.Errorf("--release-owner-id must differ from --current-owner-id")
+		}
+		return nil
+	case ReassignCommand:
+		if cfg.FromOwnerID == "" || cfg.ToOwnerID == "" {
+			return fmt.Errorf("--from-owner-id and --to-owner-id are required by %s command", ReassignCommand)
+		}
+		if cfg.FromOwnerID == cfg.ToOwnerID {
+			return fmt.Errorf("--to-owner-id must differ from --from-owner-id")
+		}
+		return cfg.validateResourcePattern()
+	case InventoryCommand:
+		return nil
+	case ReadinessCommand:
+		switch {
+		case len(cfg.Sources) == 0:
+			return fmt.Errorf("--source is required by %s command", ReadinessCommand)
+		case len(cfg.PreviousOwnerIDs) == 0:
+			return fmt.Errorf("--previous-owner-id is required by %s command", ReadinessCommand)
+		case cfg.OldKubeContext == "" || cfg.NewKubeContext == "":
+			return fmt.Errorf("--old-context and --new-context are required by %s command", ReadinessCommand)
+		case cfg.OldKubeContext == cfg.NewKubeContext:
+			return fmt.Errorf("--new-context must differ from --old-context")
+		case cfg.APIServerURL != "":
+			return fmt.Errorf("--server can't be used by %s command, old and new contexts connect to their own API servers", ReadinessCommand)
+		}
+		return nil
+	case SnapshotCommand, DiffCommand, RestoreCommand:
+		switch {
+		case cfg.SnapshotFile == "":
+			return fmt.Errorf("--file is required by %s command", cfg.Command)
+		case cfg.Command == SnapshotCommand && cfg.TXTEncryptEnabled && !cfg.AllowDecrypted:
+			return fmt.Errorf("snapshot of encrypted registry stores records decrypted, confirm it with --allow-decrypted")
+		}
+		return nil
+	case FreezeCommand, UnfreezeCommand:
+		if cfg.ParkingOwnerID == "" {
+			return fmt.Errorf("--parking-owner-id is required by %s command", cfg.Command)
+		}
+		return cfg.validateResourcePattern()
+	}
+
+	switch {
+	case len(cfg.Sources) == 0:
+		return fmt.Errorf("--source is required by %s command", ClaimCommand)
+	case cfg.Journal != "" && cfg.Resume != "":
+		return fmt.Errorf("--journal and --resume can't be used together, resumed journal is updated in place")
+	case cfg.Mode == "owner" && cfg.CurrentOwnerID == "":
+		return fmt.Errorf("--current-owner-id is required in owner mode")
+	case cfg.Mode == "owner" && len(cfg.PreviousOwnerIDs) == 0:
+		return fmt.Errorf("--previous-owner-id is required in owner mode")
+	case cfg.Mode == "adopt" && cfg.CurrentOwnerID == "":
+		return fmt.Errorf("--current-owner-id is required in adopt mode")
+	case cfg.Mode == "resource" && cfg.ResourceOwnerPolicy == ResourceOwnerCurrent && cfg.CurrentOwnerID == "":
+		return fmt.Errorf("--current-owner-id is required in resource mode with '%s' owner policy", ResourceOwnerCurrent)
+	case cfg.Mode == "resource" && cfg.ResourceOwnerPolicy == ResourceOwnerAllowed && cfg.CurrentOwnerID == "" && len(cfg.PreviousOwnerIDs) == 0:
+		return fmt.Errorf("--current-owner-id or --previous-owner-id is required in resource mode with '%s' owner policy", ResourceOwnerAllowed)
+	}
+	for _, source := range cfg.SourcePriority {
+		if source != "ingress" && source != "istio-virtualservice" {
+			return fmt.Errorf("--source-priority contains unknown source '%s'", source)
+		}
+	}
+	return nil
+}
 
+func (cfg *Config) validateResourcePattern() error {
+	if _, err := path.Match(cfg.ResourcePattern, ""); err != nil {
+		return fmt.Errorf("invalid --resource-pattern '%s': %w", cfg.ResourcePattern, err)
+	}
 	return nil
 }
+
+// splitList flattens comma separated flag values, dropping empty entries
+func splitList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_ParseFlags_OwnerIds(t *testing.T) {
+	required := []string{"--source=ingress", "--dns-zone=dummy.host"}
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "Owner mode with owners", args: []string{"--current-owner-id=cluster-2", "--previous-owner-id=cluster-1"}},
+		{name: "Owner mode without current owner", args: []string{"--previous-owner-id=cluster-1"}, wantErr: true},
+		{name: "Owner mode without previous owner", args: []string{"--current-owner-id=cluster-2"}, wantErr: true},
+		{name: "Resource mode with current owner", args: []string{"--mode=resource", "--current-owner-id=cluster-2"}},
+		{name: "Resource mode without current owner", args: []string{"--mode=resource"}, wantErr: true},
+		{name: "Resource mode allowed policy with previous owner", args: []string{"--mode=resource", "--resource-owner-policy=allowed", "--previous-owner-id=cluster-1"}},
+		{name: "Resource mode allowed policy without owners", args: []string{"--mode=resource", "--resource-owner-policy=allowed"}, wantErr: true},
+		{name: "Resource mode any policy without owners", args: []string{"--mode=resource", "--resource-owner-policy=any"}},
+		{name: "Adopt mode with current owner", args: []string{"--mode=adopt", "--current-owner-id=cluster-2"}},
+		{name: "Adopt mode without current owner", args: []string{"--mode=adopt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewConfig().ParseFlags(append(required, tt.args...))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ParseFlags_SourcePriority(t *testing.T) {
+	required := []string{"--source=ingress", "--dns-zone=dummy.host", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1"}
+
+	cfg := NewConfig()
+	err := cfg.ParseFlags(append(required, "--source-priority=istio-virtualservice, ingress", "--host-override=api.dummy.host=ingress"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"istio-virtualservice", "ingress"}, cfg.SourcePriority)
+	assert.Equal(t, map[string]string{"api.dummy.host": "ingress"}, cfg.HostOverrides)
+
+	err = NewConfig().ParseFlags(append(required, "--source-priority=service"))
+	assert.Error(t, err)
+}
+
+func TestConfig_ParseFlags_Commands(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		command string
+		wantErr bool
+	}{
+		{name: "Claim by default", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1"}, command: ClaimCommand},
+		{name: "Claim without source", args: []string{"claim", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1"}, wantErr: true},
+		{name: "Orphans without source and owners", args: []string{"orphans"}, command: OrphansCommand},
+		{name: "Orphans delete with owner", args: []string{"orphans", "--delete", "--owner-id=cluster-1"}, command: OrphansCommand},
+		{name: "Orphans delete without owner", args: []string{"orphans", "--delete"}, wantErr: true},
+		{name: "Release with source and owner", args: []string{"release", "--source=ingress", "--current-owner-id=cluster-2", "--release-owner-id=cluster-1"}, command: ReleaseCommand},
+		{name: "Release without current owner", args: []string{"release", "--source=ingress"}, wantErr: true},
+		{name: "Reassign with owners", args: []string{"reassign", "--from-owner-id=cluster-1", "--to-owner-id=cluster-2", "--host-regex=^api\\.", "--resource-pattern=ingress/*"}, command: ReassignCommand},
+		{name: "Reassign without target owner", args: []string{"reassign", "--from-owner-id=cluster-1"}, wantErr: true},
+		{name: "Reassign with invalid resource pattern", args: []string{"reassign", "--from-owner-id=cluster-1", "--to-owner-id=cluster-2", "--resource-pattern=ingress/["}, wantErr: true},
+		{name: "Freeze with defaults", args: []string{"freeze", "--owner-id=cluster-1"}, command: FreezeCommand},
+		{name: "Unfreeze without parking owner", args: []string{"unfreeze", "--parking-owner-id="}, wantErr: true},
+		{name: "Inventory", args: []string{"inventory", "--output=csv"}, command: InventoryCommand},
+		{name: "Readiness with contexts", args: []string{"readiness", "--source=ingress", "--previous-owner-id=cluster-1", "--old-context=old", "--new-context=new", "--output=json"}, command: ReadinessCommand},
+		{name: "Readiness with API server", args: []string{"readiness", "--source=ingress", "--previous-owner-id=cluster-1", "--old-context=old", "--new-context=new", "--server=https://127.0.0.1:6443"}, wantErr: true},
+		{name: "Readiness with the same context", args: []string{"readiness", "--source=ingress", "--previous-owner-id=cluster-1", "--old-context=old", "--new-context=old"}, wantErr: true},
+		{name: "Readiness without previous owner", args: []string{"readiness", "--source=ingress", "--old-context=old", "--new-context=new"}, wantErr: true},
+		{name: "Claim with markdown report", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--report-format=markdown", "--fail-on-problems"}, command: ClaimCommand},
+		{name: "Claim with unknown report format", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--report-format=yaml"}, wantErr: true},
+		{name: "Claim continuing on errors", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--on-error=continue"}, command: ClaimCommand},
+		{name: "Claim with unknown error policy", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--on-error=retry"}, wantErr: true},
+		{name: "Claim with concurrency and rate limit", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--concurrency=8", "--rate-limit=0.5"}, command: ClaimCommand},
+		{name: "Claim without workers", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--concurrency=0"}, wantErr: true},
+		{name: "Claim resuming from journal", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--resume=configmap:dns-tagger/journal"}, command: ClaimCommand},
+		{name: "Claim with journal and resume", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--journal=journal.json", "--resume=journal.json"}, wantErr: true},
+		{name: "Snapshot to file", args: []string{"snapshot", "--file=snapshot.json"}, command: SnapshotCommand},
+		{name: "Snapshot without file", args: []string{"snapshot"}, wantErr: true},
+		{name: "Diff against snapshot", args: []string{"diff", "--file=before.json", "--against=after.json", "--output=json"}, command: DiffCommand},
+		{name: "Snapshot of encrypted registry", args: []string{"snapshot", "--file=snapshot.json", "--txt-encrypt-enabled"}, wantErr: true},
+		{name: "Decrypted snapshot of encrypted registry", args: []string{"snapshot", "--file=snapshot.json", "--txt-encrypt-enabled", "--allow-decrypted"}, command: SnapshotCommand},
+		{name: "Restore snapshot", args: []string{"restore", "--file=snapshot.json", "--apply"}, command: RestoreCommand},
+		{name: "Claim with negative rate limit", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--rate-limit=-0.5"}, wantErr: true},
+		{name: "Claim with lock", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--lock", "--lock-ttl=10m", "--lock-holder=cluster-2-job"}, command: ClaimCommand},
+		{name: "Claim with too short lock", args: []string{"--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1", "--lock", "--lock-ttl=0s"}, wantErr: true},
+		{name: "Release to current owner", args: []string{"release", "--source=ingress", "--current-owner-id=cluster-2", "--release-owner-id=cluster-2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			err := cfg.ParseFlags(append([]string{"--dns-zone=dummy.host"}, tt.args...))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.command, cfg.Command)
+			}
+		})
+	}
+}
+
+func TestConfig_ChangesRateLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want float64
+	}{
+		{name: "DNSimple default", args: []string{"--apply"}, want: DNSimpleRateLimit},
+		{name: "DynamoDB default", args: []string{"--apply", "--registry=dynamodb", "--dynamodb-table=external-dns"}, want: 0},
+		{name: "Dry run default", args: []string{}, want: 0},
+		{name: "Explicit limit", args: []string{"--apply", "--rate-limit=2"}, want: 2},
+		{name: "Explicitly unlimited", args: []string{"--apply", "--rate-limit=0"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			args := append([]string{"--dns-zone=dummy.host", "--source=ingress", "--current-owner-id=cluster-2", "--previous-owner-id=cluster-1"}, tt.args...)
+			assert.NoError(t, cfg.ParseFlags(args))
+			assert.Equal(t, tt.want, cfg.ChangesRateLimit())
+		})
+	}
+}
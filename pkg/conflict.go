@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// Conflict describes host claimed by several source endpoints with different resources
+type Conflict struct {
+	Host      registry.Hostname
+	Endpoints []*registry.Endpoint
+	// Resolved endpoint used for the host, nil when conflict can't be resolved and host is skipped
+	Resolved *registry.Endpoint
+	// Reason explains how conflict was resolved or why it was not
+	Reason string
+}
+
+// resolveConflicts leaves single endpoint per host. Endpoints with the same host and resource are merged,
+// hosts with different resources are resolved by host override first and source priority second.
+// Unresolved hosts are dropped so registry records are not flipped between resources within one run
+func (s *Selector) resolveConflicts(endpoints []*registry.Endpoint) []*registry.Endpoint {
+	hosts := make([]registry.Hostname, 0)
+	hostEndpoints := make(map[registry.Hostname][]*registry.Endpoint)
+	for _, endpoint := range endpoints {
+		if _, ok := hostEndpoints[endpoint.Host]; !ok {
+			hosts = append(hosts, endpoint.Host)
+		}
+		if !hasResource(hostEndpoints[endpoint.Host], endpoint.Resource) {
+			hostEndpoints[endpoint.Host] = append(hostEndpoints[endpoint.Host], endpoint)
+		}
+	}
+
+	overrides := make(map[registry.Hostname]string, len(s.cfg.HostOverrides))
+	for host, override := range s.cfg.HostOverrides {
+		overrides[registry.NewHostname(host)] = override
+	}
+
+	resolved := make([]*registry.Endpoint, 0, len(hosts))
+	for _, host := range hosts {
+		candidates := hostEndpoints[host]
+		if len(candidates) == 1 {
+			resolved = append(resolved, candidates[0])
+			continue
+		}
+
+		conflict := s.resolveConflict(host, candidates, overrides[host])
+		if s.report != nil {
+			s.report.AddConflict(conflict)
+		}
+		if conflict.Resolved == nil {
+			log.Warnf("Skipping '%s': %s", host, conflict.Reason)
+			continue
+		}
+		log.Debugf("Using '%s' for '%s': %s", conflict.Resolved.Resource, host, conflict.Reason)
+		resolved = append(resolved, conflict.Resolved)
+	}
+	return resolved
+}
+
+// addConflictResults reports every endpoint of the hosts skipped because of unresolved conflicts with its reason
+func (s *Selector) addConflictResults(zones []*registry.Zone) {
+	if s.report == nil {
+		return
+	}
+	for _, conflict := range s.report.UnresolvedConflicts() {
+		for _, endpoint := range conflict.Endpoints {
+			s.addResult(endpoint, registry.FindZone(zones, endpoint.Host), nil, OutcomeConflict, errors.New(conflict.Reason))
+		}
+	}
+}
+
+func (s *Selector) resolveConflict(host registry.Hostname, candidates []*registry.Endpoint, override string) *Conflict {
+	conflict := &Conflict{Host: host, Endpoints: candidates}
+	if override != "" {
+		matched := filterEndpoints(candidates, func(e *registry.Endpoint) bool {
+			return e.Source == override || e.Resource == override
+		})
+		if len(matched) == 1 {
+			conflict.Resolved = matched[0]
+			conflict.Reason = fmt.Sprintf("host override '%s'", override)
+		} else {
+			conflict.Reason = fmt.Sprintf("host override '%s' matched %d endpoints", override, len(matched))
+		}
+		return conflict
+	}
+
+	for _, source := range s.cfg.SourcePriority {
+		matched := filterEndpoints(candidates, func(e *registry.Endpoint) bool { return e.Source == source })
+		if len(matched) == 0 {
+			continue
+		}
+		if len(matched) == 1 {
+			conflict.Resolved = matched[0]
+			conflict.Reason = fmt.Sprintf("source priority '%s'", source)
+		} else {
+			conflict.Reason = fmt.Sprintf("source '%s' has %d endpoints with different resources", source, len(matched))
+		}
+		return conflict
+	}
+
+	conflict.Reason = "no host override or source priority matches the endpoints"
+	return conflict
+}
+
+func hasResource(endpoints []*registry.Endpoint, resource string) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.Resource == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func filterEndpoints(endpoints []*registry.Endpoint, predicate func(*registry.Endpoint) bool) []*registry.Endpoint {
+	matched := make([]*registry.Endpoint, 0)
+	for _, endpoint := range endpoints {
+		if predicate(endpoint) {
+			matched = append(matched, endpoint)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector_ResolveConflicts(t *testing.T) {
+	ingress := registry.NewEndpoint(testEndpointHost, "ingress/test/webserver", "ingress")
+	ingressDuplicate := registry.NewEndpoint(testEndpointHost, "ingress/test/webserver", "ingress")
+	ingressOther := registry.NewEndpoint(testEndpointHost, "ingress/test/webserver2", "ingress")
+	virtualService := registry.NewEndpoint("WebServer.Dummy.Host.", "virtualservice/test/webserver", "istio-virtualservice")
+	other := registry.NewEndpoint("api.dummy.host", "ingress/test/api", "ingress")
+
+	tests := []struct {
+		name       string
+		priority   []string
+		overrides  map[string]string
+		endpoints  []*registry.Endpoint
+		want       []*registry.Endpoint
+		unresolved int
+	}{
+		{name: "Same resource is merged", endpoints: []*registry.Endpoint{ingress, ingressDuplicate, other}, want: []*registry.Endpoint{ingress, other}},
+		{name: "Conflict without priority is skipped", endpoints: []*registry.Endpoint{ingress, virtualService, other}, want: []*registry.Endpoint{other}, unresolved: 1},
+		{name: "Source priority", priority: []string{"istio-virtualservice", "ingress"}, endpoints: []*registry.Endpoint{ingress, virtualService, other}, want: []*registry.Endpoint{virtualService, other}},
+		{name: "Lower source priority", priority: []string{"istio-virtualservice", "ingress"}, endpoints: []*registry.Endpoint{ingress, other}, want: []*registry.Endpoint{ingress, other}},
+		{name: "Ambiguous source priority", priority: []string{"ingress"}, endpoints: []*registry.Endpoint{ingress, ingressOther, virtualService}, want: []*registry.Endpoint{}, unresolved: 1},
+		{name: "Host override by source", priority: []string{"istio-virtualservice"}, overrides: map[string]string{"WebServer.Dummy.Host": "ingress"}, endpoints: []*registry.Endpoint{ingress, virtualService}, want: []*registry.Endpoint{ingress}},
+		{name: "Host override by resource", overrides: map[string]string{testEndpointHost: "ingress/test/webserver2"}, endpoints: []*registry.Endpoint{ingress, ingressOther, virtualService}, want: []*registry.Endpoint{ingressOther}},
+		{name: "Host override without match", priority: []string{"ingress"}, overrides: map[string]string{testEndpointHost: "ingress/test/missing"}, endpoints: []*registry.Endpoint{ingress, virtualService}, want: []*registry.Endpoint{}, unresolved: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := NewSelector(&Config{SourcePriority: tt.priority, HostOverrides: tt.overrides}, &mockProvider{})
+
+			assert.Equal(t, tt.want, selector.resolveConflicts(tt.endpoints))
+			assert.Len(t, selector.Report().UnresolvedConflicts(), tt.unresolved)
+		})
+	}
+}
+
+func TestSelector_ClaimEndpointsOwnership_ConflictResults(t *testing.T) {
+	selector := NewSelector(cfg, &mockProvider{})
+	endpoints := []*registry.Endpoint{
+		registry.NewEndpoint(testEndpointHost, "ingress/test/webserver", "ingress"),
+		registry.NewEndpoint(testEndpointHost, "virtualservice/test/webserver", "istio-virtualservice"),
+	}
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{registry.NewZone("dummy.host")})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updates)
+
+	results := selector.Report().Results
+	assert.Len(t, results, 2, "Every endpoint of skipped host listed")
+	for i, result := range results {
+		assert.Equal(t, endpoints[i].Resource, result.Resource)
+		assert.Equal(t, OutcomeConflict, result.Outcome)
+		assert.Equal(t, "dummy.host", result.Zone)
+		assert.Equal(t, "no host override or source priority matches the endpoints", result.Error)
+	}
+	assert.True(t, selector.Report().HasProblems(), "Unresolved conflict requires attention")
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// Policies for provider errors during claim
+const (
+	// OnErrorAbort stops processing on the first provider error
+	OnErrorAbort = "abort"
+	// OnErrorContinue processes remaining records and endpoints, failures are returned together at the end
+	OnErrorContinue = "continue"
+)
+
+// RecordError is a provider failure to change registry record of the endpoint
+type RecordError struct {
+	Endpoint *registry.Endpoint
+	Record   *registry.Record
+	Err      error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("registry record '%s' of '%s': %s", e.Record.Name, e.Endpoint.Host, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// fail records failed registry record in the report. Error is returned when processing should be aborted,
+// otherwise it is collected and returned by processEndpoints after all endpoints are processed
+func (s *Selector) fail(endpoint *registry.Endpoint, zone *registry.Zone, record *registry.Record, err error) error {
+	s.addResult(endpoint, zone, record, OutcomeFailed, err)
+	recordErr := &RecordError{Endpoint: endpoint, Record: record, Err: err}
+	if s.cfg.OnError == OnErrorContinue {
+		s.failuresMu.Lock()
+		defer s.failuresMu.Unlock()
+		s.failures = append(s.failures, recordErr)
+		return nil
+	}
+	return recordErr
+}
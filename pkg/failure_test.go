@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSelector_ClaimEndpointsOwnership_OnError(t *testing.T) {
+	apiErr := errors.New("api error")
+	endpoints := []*registry.Endpoint{
+		{Host: "webserver.dummy.host", Resource: testEndpointResource},
+		{Host: "admin.dummy.host", Resource: testEndpointResource},
+		{Host: "docs.dummy.host", Resource: testEndpointResource},
+	}
+
+	tests := []struct {
+		name     string
+		onError  string
+		updates  int
+		outcomes []string
+	}{
+		{name: "Abort on the first error", onError: OnErrorAbort, updates: 0, outcomes: []string{OutcomeFailed}},
+		{name: "Continue after errors", onError: OnErrorContinue, updates: 1, outcomes: []string{OutcomeFailed, OutcomeUpdated, OutcomeFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := registry.NewZone("dummy.host")
+			for _, endpoint := range endpoints {
+				host := registry.NewHost(string(endpoint.Host), "A", "127.0.0.1")
+				host.AddRegistryRecord(&registry.Record{Name: "registry1-" + endpoint.Host, Owner: "cluster-1", Resource: testEndpointResource})
+				zone.AddHost(host)
+			}
+			testProvider := &mockProvider{}
+			testProvider.On("UpdateRegistryRecord", mock.Anything, zone, mock.MatchedBy(func(record *registry.Record) bool {
+				return record.Name == "registry1-admin.dummy.host"
+			})).Return(1, nil)
+			testProvider.On("UpdateRegistryRecord", mock.Anything, zone, mock.Anything).Return(0, apiErr)
+			selector := NewSelector(&Config{CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1"}, OnError: tt.onError}, testProvider)
+
+			updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+
+			assert.ErrorIs(t, err, apiErr)
+			var recordErr *RecordError
+			assert.ErrorAs(t, err, &recordErr)
+			assert.Equal(t, "registry1-webserver.dummy.host", string(recordErr.Record.Name), "Failed record returned with the error")
+			assert.Equal(t, tt.updates, updates)
+			var outcomes []string
+			for _, result := range selector.Report().Results {
+				outcomes = append(outcomes, result.Outcome)
+			}
+			assert.Equal(t, tt.outcomes, outcomes)
+		})
+	}
+}
+
+func TestSelector_ClaimEndpointsOwnership_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testProvider := &mockProvider{}
+	selector := NewSelector(&Config{CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1"}, OnError: OnErrorContinue}, testProvider)
+	endpoints := []*registry.Endpoint{{Host: testEndpointHost, Resource: testEndpointResource}}
+
+	_, err := selector.ClaimEndpointsOwnership(ctx, endpoints, []*registry.Zone{createTestZone("cluster-1", testEndpointResource)})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	testProvider.AssertNotCalled(t, "UpdateRegistryRecord")
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// FrozenOwnerLabel keeps original owner of the registry record parked by freeze command
+const FrozenOwnerLabel = "frozen-owner"
+
+// FindFreezeRecords returns registry records to park: records of selected owners matching host and resource filters
+// that are not frozen yet
+func (s *Selector) FindFreezeRecords(zones []*registry.Zone) []*ZoneRecord {
+	return s.selectZoneRecords(zones, func(record *registry.Record) bool {
+		if record.Owner == s.cfg.ParkingOwnerID || record.Labels[FrozenOwnerLabel] != "" {
+			return false
+		}
+		return len(s.cfg.FreezeOwnerIDs) == 0 || containsString(s.cfg.FreezeOwnerIDs, record.Owner)
+	})
+}
+
+// FindUnfreezeRecords returns parked registry records with known original owner matching host and resource filters
+func (s *Selector) FindUnfreezeRecords(zones []*registry.Zone) []*ZoneRecord {
+	return s.selectZoneRecords(zones, func(record *registry.Record) bool {
+		frozenOwner := record.Labels[FrozenOwnerLabel]
+		if record.Owner != s.cfg.ParkingOwnerID || frozenOwner == "" {
+			return false
+		}
+		return len(s.cfg.FreezeOwnerIDs) == 0 || containsString(s.cfg.FreezeOwnerIDs, frozenOwner)
+	})
+}
+
+// FreezeRecords moves registry records to parking owner id, so no External DNS instance changes them,
+// original owner is kept in the registry label
+func (s *Selector) FreezeRecords(ctx context.Context, records []*ZoneRecord) (updatedRecords int, err error) {
+	for _, frozenRecord := range records {
+		log.Infof("Freezing '%s' with parking owner '%s'", frozenRecord.Record, s.cfg.ParkingOwnerID)
+		updatedRecord := frozenRecord.Record.NewRecord(s.cfg.ParkingOwnerID, frozenRecord.Record.Resource)
+		updatedRecord.SetLabel(FrozenOwnerLabel, frozenRecord.Record.Owner)
+		updates, err := s.updateRecord(ctx, frozenRecord.Zone, frozenRecord.Record, updatedRecord)
+		updatedRecords += updates
+		if err != nil {
+			return updatedRecords, err
+		}
+	}
+	return updatedRecords, nil
+}
+
+// UnfreezeRecords restores original owners of parked registry records
+func (s *Selector) UnfreezeRecords(ctx context.Context, records []*ZoneRecord) (updatedRecords int, err error) {
+	for _, frozenRecord := range records {
+		frozenOwner := frozenRecord.Record.Labels[FrozenOwnerLabel]
+		log.Infof("Unfreezing '%s' to owner '%s'", frozenRecord.Record, frozenOwner)
+		updatedRecord := frozenRecord.Record.NewRecord(frozenOwner, frozenRecord.Record.Resource)
+		updatedRecord.RemoveLabel(FrozenOwnerLabel)
+		updates, err := s.updateRecord(ctx, frozenRecord.Zone, frozenRecord.Record, updatedRecord)
+		updatedRecords += updates
+		if err != nil {
+			return updatedRecords, err
+		}
+	}
+	return updatedRecords, nil
+}
@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSelector_FreezeRecords(t *testing.T) {
+	testProvider := &mockProvider{}
+	zone := createReassignZone()
+	freezeCfg := &Config{ParkingOwnerID: "parking", FreezeOwnerIDs: []string{"cluster-1"}, HostRegex: regexp.MustCompile(`^webserver\.`)}
+	selector := NewSelector(freezeCfg, testProvider)
+
+	records := selector.FindFreezeRecords([]*registry.Zone{zone})
+	assert.Equal(t, []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[0]}}, records)
+
+	var frozen *registry.Record
+	testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil).Run(func(args mock.Arguments) {
+		frozen = args.Get(2).(*registry.Record)
+	})
+	updates, err := selector.FreezeRecords(context.Background(), records)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, "heritage=external-dns,external-dns/owner=parking,external-dns/resource=ingress/production/webserver,external-dns/frozen-owner=cluster-1", frozen.Info())
+
+	// Parked record is read back from registry
+	zone.Hosts[0].RegistryRecords[0] = frozen
+	zone.RegistryRecords[0] = frozen
+	assert.Empty(t, selector.FindFreezeRecords([]*registry.Zone{zone}), "Frozen records are not frozen twice")
+	records = selector.FindUnfreezeRecords([]*registry.Zone{zone})
+	assert.Equal(t, []*ZoneRecord{{Zone: zone, Record: frozen}}, records)
+
+	updates, err = selector.UnfreezeRecords(context.Background(), records)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+	restored := testProvider.Calls[1].Arguments.Get(2).(*registry.Record)
+	assert.Equal(t, "heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=ingress/production/webserver", restored.Info())
+}
@@ -0,0 +1,174 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// Output formats of reports written to stdout
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputCSV   = "csv"
+)
+
+// InventoryHost describes ownership of the host record set
+type InventoryHost struct {
+	Zone   string   `json:"zone"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+	// Owner and Resource of canonical registry record, empty for unmanaged hosts
+	Owner           string   `json:"owner"`
+	Resource        string   `json:"resource"`
+	RegistryRecords []string `json:"registryRecords"`
+}
+
+// Inventory lists hosts of the zones grouped by owner
+type Inventory struct {
+	Hosts []*InventoryHost `json:"hosts"`
+	// Owners counts managed host names of every owner, record sets of the same host name are counted once
+	Owners map[string]int `json:"owners"`
+	// Unmanaged hosts have no registry records
+	Unmanaged []*InventoryHost `json:"unmanaged"`
+	// MultipleRecords hosts have registry records that disagree or several registry records of the same format
+	MultipleRecords []*InventoryHost `json:"multipleRecords"`
+}
+
+// NewInventory collects hosts of the zones, sorted by owner and host name
+func NewInventory(zones []*registry.Zone) *Inventory {
+	inventory := &Inventory{
+		Hosts:           make([]*InventoryHost, 0),
+		Owners:          make(map[string]int),
+		Unmanaged:       make([]*InventoryHost, 0),
+		MultipleRecords: make([]*InventoryHost, 0),
+	}
+	for _, zone := range zones {
+		hostOwners := make(map[string]bool)
+		for _, host := range zone.Hosts {
+			inventoryHost := &InventoryHost{
+				Zone:            string(zone.Name),
+				Name:            string(host.Name),
+				Type:            host.RecordType,
+				Values:          host.Values,
+				RegistryRecords: make([]string, 0, len(host.RegistryRecords)),
+			}
+			for _, record := range host.RegistryRecords {
+				inventoryHost.RegistryRecords = append(inventoryHost.RegistryRecords, string(record.Name))
+			}
+			inventory.Hosts = append(inventory.Hosts, inventoryHost)
+
+			if !host.IsManaged() {
+				inventory.Unmanaged = append(inventory.Unmanaged, inventoryHost)
+				continue
+			}
+			canonical := host.RegistryRecords[0]
+			if conflict := registry.AnalyzeRegistryRecords(host); conflict != nil {
+				canonical = conflict.Canonical
+				if hasRecordProblem(conflict) {
+					inventory.MultipleRecords = append(inventory.MultipleRecords, inventoryHost)
+				}
+			}
+			inventoryHost.Owner = canonical.Owner
+			inventoryHost.Resource = canonical.Resource
+			if key := string(host.Name) + "#" + canonical.Owner; !hostOwners[key] {
+				hostOwners[key] = true
+				inventory.Owners[canonical.Owner]++
+			}
+		}
+	}
+
+	sort.SliceStable(inventory.Hosts, func(i, j int) bool {
+		if inventory.Hosts[i].Owner != inventory.Hosts[j].Owner {
+			return inventory.Hosts[i].Owner < inventory.Hosts[j].Owner
+		}
+		return inventory.Hosts[i].Name < inventory.Hosts[j].Name
+	})
+	return inventory
+}
+
+// hasRecordProblem checks whether registry records of the host disagree or repeat the same format. Legacy record
+// next to new format record is what External DNS creates for every record set and is not reported
+func hasRecordProblem(conflict *registry.RecordConflict) bool {
+	if conflict.OwnerMismatch || conflict.ResourceMismatch || len(conflict.Duplicates) > 0 {
+		return true
+	}
+	legacy, recordType := 0, 0
+	for _, record := range conflict.Host.RegistryRecords {
+		if record.RecordType == "" {
+			legacy++
+		} else {
+			recordType++
+		}
+	}
+	return legacy > 1 || recordType > 1
+}
+
+// Write renders inventory in table, json or csv format. CSV contains hosts only
+func (i *Inventory) Write(w io.Writer, format string) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(i)
+	case OutputCSV:
+		return i.writeCSV(w)
+	case OutputTable:
+		return i.writeTable(w)
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+func (i *Inventory) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{"zone", "host", "type", "values", "owner", "resource", "registry_records"}}
+	for _, host := range i.Hosts {
+		rows = append(rows, []string{host.Zone, host.Name, host.Type, strings.Join(host.Values, " "), host.Owner, host.Resource, strings.Join(host.RegistryRecords, " ")})
+	}
+	return writer.WriteAll(rows)
+}
+
+func (i *Inventory) writeTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "OWNER\tHOST\tTYPE\tVALUES\tRESOURCE\tREGISTRY RECORDS")
+	for _, host := range i.Hosts {
+		owner := host.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", owner, host.Name, host.Type, strings.Join(host.Values, ","), host.Resource, strings.Join(host.RegistryRecords, ","))
+	}
+
+	owners := make([]string, 0, len(i.Owners))
+	for owner := range i.Owners {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	fmt.Fprintln(table, "\nOWNER\tHOSTS")
+	for _, owner := range owners {
+		fmt.Fprintf(table, "%s\t%d\n", owner, i.Owners[owner])
+	}
+	fmt.Fprintf(table, "%s\t%d\n", "unmanaged", len(i.Unmanaged))
+
+	writeHostList(table, "\nUnmanaged hosts:", i.Unmanaged)
+	writeHostList(table, "\nHosts with conflicting registry records:", i.MultipleRecords)
+	return table.Flush()
+}
+
+func writeHostList(w io.Writer, title string, hosts []*InventoryHost) {
+	if len(hosts) == 0 {
+		return
+	}
+	fmt.Fprintln(w, title)
+	for _, host := range hosts {
+		fmt.Fprintf(w, "  %s\t%s\n", host.Name, host.Type)
+	}
+}
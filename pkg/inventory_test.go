@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func createInventoryZone() *registry.Zone {
+	zone := registry.NewZone("dummy.host")
+	webserver := registry.NewHost("webserver.dummy.host", "A", "127.0.0.1", "127.0.0.2")
+	webserver.AddRegistryRecord(&registry.Record{Name: "edns-webserver.dummy.host", Owner: "cluster-1", Resource: "ingress/test/webserver"})
+	webserver.AddRegistryRecord(&registry.Record{Name: "edns-a-webserver.dummy.host", RecordType: "A", Owner: "cluster-2", Resource: "ingress/test/webserver"})
+	api := registry.NewHost("api.dummy.host", "CNAME", "webserver.dummy.host")
+	api.AddRegistryRecord(&registry.Record{Name: "edns-cname-api.dummy.host", RecordType: "CNAME", Owner: "cluster-1", Resource: "ingress/test/api"})
+	zone.AddHost(webserver)
+	zone.AddHost(api)
+	www := &registry.Record{Name: "edns-www.dummy.host", Owner: "cluster-1", Resource: "ingress/test/www"}
+	for _, recordType := range []string{"A", "AAAA"} {
+		host := registry.NewHost("www.dummy.host", recordType, "127.0.0.1")
+		host.AddRegistryRecord(www)
+		host.AddRegistryRecord(&registry.Record{Name: registry.Hostname("edns-" + strings.ToLower(recordType) + "-www.dummy.host"), RecordType: recordType, Owner: "cluster-1", Resource: "ingress/test/www"})
+		zone.AddHost(host)
+	}
+	zone.AddHost(registry.NewHost("mail.dummy.host", "A", "127.0.0.3"))
+	return zone
+}
+
+func TestNewInventory(t *testing.T) {
+	inventory := NewInventory([]*registry.Zone{createInventoryZone()})
+
+	var hosts []string
+	for _, host := range inventory.Hosts {
+		hosts = append(hosts, host.Owner+"/"+host.Name)
+	}
+	assert.Equal(t, []string{"/mail.dummy.host", "cluster-1/api.dummy.host", "cluster-1/www.dummy.host", "cluster-1/www.dummy.host", "cluster-2/webserver.dummy.host"}, hosts, "Sorted by owner and host")
+	assert.Equal(t, map[string]int{"cluster-1": 2, "cluster-2": 1}, inventory.Owners, "Owner of canonical record counted once per host name")
+	assert.Len(t, inventory.Unmanaged, 1)
+	assert.Equal(t, "mail.dummy.host", inventory.Unmanaged[0].Name)
+	assert.Len(t, inventory.MultipleRecords, 1, "Legacy and new format records that agree are not reported")
+	assert.Equal(t, []string{"edns-webserver.dummy.host", "edns-a-webserver.dummy.host"}, inventory.MultipleRecords[0].RegistryRecords)
+}
+
+func TestInventory_Write(t *testing.T) {
+	inventory := NewInventory([]*registry.Zone{createInventoryZone()})
+
+	var csvOutput bytes.Buffer
+	assert.NoError(t, inventory.Write(&csvOutput, OutputCSV))
+	assert.Equal(t, `zone,host,type,values,owner,resource,registry_records
+dummy.host,mail.dummy.host,A,127.0.0.3,,,
+dummy.host,api.dummy.host,CNAME,webserver.dummy.host,cluster-1,ingress/test/api,edns-cname-api.dummy.host
+dummy.host,www.dummy.host,A,127.0.0.1,cluster-1,ingress/test/www,edns-www.dummy.host edns-a-www.dummy.host
+dummy.host,www.dummy.host,AAAA,127.0.0.1,cluster-1,ingress/test/www,edns-www.dummy.host edns-aaaa-www.dummy.host
+dummy.host,webserver.dummy.host,A,127.0.0.1 127.0.0.2,cluster-2,ingress/test/webserver,edns-webserver.dummy.host edns-a-webserver.dummy.host
+`, csvOutput.String())
+
+	var jsonOutput bytes.Buffer
+	assert.NoError(t, inventory.Write(&jsonOutput, OutputJSON))
+	assert.Contains(t, jsonOutput.String(), `"owners": {`)
+
+	var tableOutput bytes.Buffer
+	assert.NoError(t, inventory.Write(&tableOutput, OutputTable))
+	assert.Contains(t, tableOutput.String(), "Hosts with conflicting registry records:\n  webserver.dummy.host  A")
+
+	assert.Error(t, inventory.Write(&tableOutput, "yaml"))
+}
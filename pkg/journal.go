@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// JournalVersion is the format version of the journal written by this build
+const JournalVersion = 1
+
+// Registry record changes
+const (
+	ActionUpdate = "update"
+	ActionCreate = "create"
+	ActionDelete = "delete"
+)
+
+// Statuses of journal entries
+const (
+	// JournalPending change was planned but not applied, dry run changes stay pending
+	JournalPending = "pending"
+	// JournalApplied change was applied by the provider
+	JournalApplied = "applied"
+	// JournalFailed provider failed to apply the change
+	JournalFailed = "failed"
+)
+
+// errRecordChanged is returned when registry record differs from the one the pending change was planned for
+var errRecordChanged = errors.New("registry record changed since the change was planned")
+
+// JournalEntry is a planned registry record change and its status
+type JournalEntry struct {
+	Zone       string `json:"zone"`
+	Action     string `json:"action"`
+	Record     string `json:"record"`
+	RecordType string `json:"recordType,omitempty"`
+	// PreviousOwner and PreviousResource of the registry record before the change, empty for created records
+	PreviousOwner    string `json:"previousOwner,omitempty"`
+	PreviousResource string `json:"previousResource,omitempty"`
+	// Owner and Resource set by the change, empty for deleted records
+	Owner    string `json:"owner,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (e *JournalEntry) key() string {
+	return journalKey(e.Zone, e.Action, e.Record, e.RecordType)
+}
+
+func journalKey(zone, action, record, recordType string) string {
+	return zone + "#" + action + "#" + record + "#" + recordType
+}
+
+// Journal keeps progress of the claim run in the store, so interrupted run can be resumed
+type Journal struct {
+	Version        int             `json:"version"`
+	Mode           string          `json:"mode"`
+	CurrentOwnerID string          `json:"currentOwnerId"`
+	Entries        []*JournalEntry `json:"entries"`
+
+	store   JournalStore
+	apply   bool
+	entries map[string]*JournalEntry
+	mu      sync.Mutex
+}
+
+// NewJournal starts empty journal of the run configured by cfg
+func NewJournal(store JournalStore, cfg *Config) *Journal {
+	return &Journal{
+		Version:        JournalVersion,
+		Mode:           cfg.Mode,
+		CurrentOwnerID: cfg.CurrentOwnerID,
+		Entries:        make([]*JournalEntry, 0),
+		store:          store,
+		apply:          cfg.Apply,
+		entries:        make(map[string]*JournalEntry),
+	}
+}
+
+// ResumeJournal loads journal of interrupted run from the store. Journal must be written by the run
+// with the same mode and current owner id
+func ResumeJournal(ctx context.Context, store JournalStore, cfg *Config) (*Journal, error) {
+	data, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("journal %s not found", store)
+	}
+	journal := NewJournal(store, cfg)
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %w", store, err)
+	}
+	if journal.Version != JournalVersion {
+		return nil, fmt.Errorf("journal %s has unsupported version %d", store, journal.Version)
+	}
+	if journal.Mode != cfg.Mode || journal.CurrentOwnerID != cfg.CurrentOwnerID {
+		return nil, fmt.Errorf("journal %s was written in '%s' mode for '%s' owner", store, journal.Mode, journal.CurrentOwnerID)
+	}
+	for _, entry := range journal.Entries {
+		journal.entries[entry.key()] = entry
+	}
+	return journal, nil
+}
+
+// Plan records pending change of the registry record, record is nil for created records and changed is nil
+// for deleted records. Change of the resumed run is planned again only if the record still has owner and resource
+// the change was planned for. Applied change is planned again as well, the record was reverted after it was applied
+func (j *Journal) Plan(ctx context.Context, zone *registry.Zone, action string, record *registry.Record, changed *registry.Record) (*JournalEntry, error) {
+	target := changed
+	if target == nil {
+		target = record
+	}
+	entry := &JournalEntry{Zone: string(zone.Name), Action: action, Record: string(target.Name), RecordType: target.RecordType, Status: JournalPending}
+	if record != nil {
+		entry.PreviousOwner = record.Owner
+		entry.PreviousResource = record.Resource
+	}
+	if changed != nil {
+		entry.Owner = changed.Owner
+		entry.Resource = changed.Resource
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if existing, ok := j.entries[entry.key()]; ok {
+		if existing.PreviousOwner != entry.PreviousOwner || existing.PreviousResource != entry.PreviousResource {
+			return existing, errRecordChanged
+		}
+		if existing.Status == JournalApplied {
+			log.Warnf("Registry record '%s' was reverted after the journal change was applied, applying it again", existing.Record)
+		}
+		*existing = *entry
+		return existing, j.save(ctx)
+	}
+	j.Entries = append(j.Entries, entry)
+	j.entries[entry.key()] = entry
+	return entry, j.save(ctx)
+}
+
+// Finish records result of the change, dry run changes stay pending
+func (j *Journal) Finish(ctx context.Context, entry *JournalEntry, err error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case err != nil:
+		entry.Status = JournalFailed
+		entry.Error = err.Error()
+	case j.apply:
+		entry.Status = JournalApplied
+		entry.Error = ""
+	}
+	return j.save(ctx)
+}
+
+func (j *Journal) save(ctx context.Context) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := j.store.Save(ctx, data); err != nil {
+		return fmt.Errorf("failed to save journal %s: %w", j.store, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// configMapJournalPrefix selects ConfigMap journal location, e.g. configmap:dns-tagger/migration-journal
+const configMapJournalPrefix = "configmap:"
+
+// configMapJournalKey is the ConfigMap data key holding the journal
+const configMapJournalKey = "journal.json"
+
+// JournalStore persists journal between runs
+type JournalStore interface {
+	// Load returns saved journal or nil when it does not exist
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+	String() string
+}
+
+// NewJournalStore returns ConfigMap store for configmap:namespace/name locations and file store otherwise.
+// Kubernetes client is created only for ConfigMap stores
+func NewJournalStore(location string, kubeClient func() (kubernetes.Interface, error)) (JournalStore, error) {
+	if !strings.HasPrefix(location, configMapJournalPrefix) {
+		return &FileJournalStore{Path: location}, nil
+	}
+	namespace, name, found := strings.Cut(strings.TrimPrefix(location, configMapJournalPrefix), "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid journal location '%s', expected %snamespace/name", location, configMapJournalPrefix)
+	}
+	client, err := kubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigMapJournalStore{Client: client, Namespace: namespace, Name: name}, nil
+}
+
+// FileJournalStore keeps journal in the local file
+type FileJournalStore struct {
+	Path string
+}
+
+func (s *FileJournalStore) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save replaces the file atomically, so interrupted run never leaves partially written journal
+func (s *FileJournalStore) Save(_ context.Context, data []byte) error {
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return err
+	}
+	// permissions of temporary file left by interrupted run are not changed by WriteFile
+	return os.Chmod(s.Path, 0o600)
+}
+
+func (s *FileJournalStore) String() string {
+	return fmt.Sprintf("file '%s'", s.Path)
+}
+
+// ConfigMapJournalStore keeps journal in the ConfigMap, so it survives restarts of the job pod
+type ConfigMapJournalStore struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+func (s *ConfigMapJournalStore) Load(ctx context.Context) ([]byte, error) {
+	configMap, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := configMap.Data[configMapJournalKey]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(data), nil
+}
+
+// Save ignores cancellation of the run context, so the last progress is recorded on termination
+func (s *ConfigMapJournalStore) Save(_ context.Context, data []byte) error {
+	ctx := context.Background()
+	configMaps := s.Client.CoreV1().ConfigMaps(s.Namespace)
+	configMap, err := configMaps.Get(ctx, s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			Data:       map[string]string{configMapJournalKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[configMapJournalKey] = string(data)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *ConfigMapJournalStore) String() string {
+	return fmt.Sprintf("ConfigMap '%s/%s'", s.Namespace, s.Name)
+}
@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createJournalZone() (*registry.Zone, []*registry.Endpoint) {
+	zone := registry.NewZone("dummy.host")
+	var endpoints []*registry.Endpoint
+	for _, name := range []string{"webserver.dummy.host", "admin.dummy.host", "docs.dummy.host"} {
+		host := registry.NewHost(name, "A", "127.0.0.1")
+		host.AddRegistryRecord(&registry.Record{Name: registry.Hostname("registry1-" + name), Owner: "cluster-1", Resource: testEndpointResource})
+		zone.AddHost(host)
+		endpoints = append(endpoints, &registry.Endpoint{Host: registry.Hostname(name), Resource: testEndpointResource})
+	}
+	return zone, endpoints
+}
+
+func readJournal(t *testing.T, path string) *Journal {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	journal := &Journal{}
+	assert.NoError(t, json.Unmarshal(data, journal))
+	return journal
+}
+
+func TestSelector_ClaimEndpointsOwnership_Journal(t *testing.T) {
+	for _, apply := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "journal.json")
+		journalCfg := &Config{Mode: "owner", CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1"}, Apply: apply}
+		testProvider := &mockProvider{}
+		testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+		selector := NewSelector(journalCfg, testProvider)
+		selector.UseJournal(NewJournal(&FileJournalStore{Path: path}, journalCfg))
+		zone, endpoints := createJournalZone()
+
+		updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, updates)
+
+		journal := readJournal(t, path)
+		assert.Equal(t, JournalVersion, journal.Version)
+		assert.Equal(t, currentOwnerId, journal.CurrentOwnerID)
+		assert.Len(t, journal.Entries, 3)
+		expectedStatus := JournalApplied
+		if !apply {
+			expectedStatus = JournalPending
+		}
+		assert.Equal(t, &JournalEntry{
+			Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-webserver.dummy.host",
+			PreviousOwner: "cluster-1", PreviousResource: testEndpointResource,
+			Owner: currentOwnerId, Resource: testEndpointResource, Status: expectedStatus,
+		}, journal.Entries[0], "Dry run changes stay pending")
+	}
+}
+
+func TestSelector_ClaimEndpointsOwnership_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journalCfg := &Config{Mode: "owner", CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1"}, Apply: true}
+	interrupted := NewJournal(&FileJournalStore{Path: path}, journalCfg)
+	interrupted.Entries = []*JournalEntry{
+		{Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-webserver.dummy.host", PreviousOwner: "cluster-1", PreviousResource: testEndpointResource, Owner: currentOwnerId, Resource: testEndpointResource, Status: JournalApplied},
+		{Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-admin.dummy.host", PreviousOwner: "cluster-1", PreviousResource: testEndpointResource, Owner: currentOwnerId, Resource: testEndpointResource, Status: JournalPending},
+		{Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-docs.dummy.host", PreviousOwner: "cluster-0", PreviousResource: testEndpointResource, Owner: currentOwnerId, Resource: testEndpointResource, Status: JournalFailed},
+	}
+	assert.NoError(t, interrupted.save(context.Background()))
+
+	journal, err := ResumeJournal(context.Background(), &FileJournalStore{Path: path}, journalCfg)
+	assert.NoError(t, err)
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	selector := NewSelector(journalCfg, testProvider)
+	selector.UseJournal(journal)
+	zone, endpoints := createJournalZone()
+	zone.Hosts[0].RegistryRecords[0].Owner = currentOwnerId
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Only pending change with the same previous owner applied")
+	testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", 1)
+	var outcomes []string
+	for _, result := range selector.Report().Results {
+		outcomes = append(outcomes, result.Outcome)
+	}
+	assert.Equal(t, []string{OutcomeUpToDate, OutcomeUpdated, OutcomeChanged}, outcomes)
+	var statuses []string
+	for _, entry := range readJournal(t, path).Entries {
+		statuses = append(statuses, entry.Status)
+	}
+	assert.Equal(t, []string{JournalApplied, JournalApplied, JournalFailed}, statuses)
+}
+
+func TestSelector_ClaimEndpointsOwnership_ResumeRevertedChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journalCfg := &Config{Mode: "owner", CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1", "cluster-0"}, Apply: true}
+	interrupted := NewJournal(&FileJournalStore{Path: path}, journalCfg)
+	interrupted.Entries = []*JournalEntry{
+		{Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-webserver.dummy.host", PreviousOwner: "cluster-1", PreviousResource: testEndpointResource, Owner: currentOwnerId, Resource: testEndpointResource, Status: JournalApplied},
+		{Zone: "dummy.host", Action: ActionUpdate, Record: "registry1-admin.dummy.host", PreviousOwner: "cluster-1", PreviousResource: testEndpointResource, Owner: currentOwnerId, Resource: testEndpointResource, Status: JournalApplied},
+	}
+	assert.NoError(t, interrupted.save(context.Background()))
+
+	journal, err := ResumeJournal(context.Background(), &FileJournalStore{Path: path}, journalCfg)
+	assert.NoError(t, err)
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	selector := NewSelector(journalCfg, testProvider)
+	selector.UseJournal(journal)
+	zone, endpoints := createJournalZone()
+	// admin record was rewritten by another owner after the change was applied, webserver record was reverted
+	zone.Hosts[1].RegistryRecords[0].Owner = "cluster-0"
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, updates, "Reverted and new changes applied")
+	var outcomes []string
+	for _, result := range selector.Report().Results {
+		outcomes = append(outcomes, result.Outcome)
+	}
+	assert.Equal(t, []string{OutcomeUpdated, OutcomeChanged, OutcomeUpdated}, outcomes, "Applied changes are never reported up to date")
+	var statuses []string
+	for _, entry := range readJournal(t, path).Entries {
+		statuses = append(statuses, entry.Status)
+	}
+	assert.Equal(t, []string{JournalApplied, JournalApplied, JournalApplied}, statuses)
+}
+
+func TestResumeJournal_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	store := &FileJournalStore{Path: path}
+	ownerCfg := &Config{Mode: "owner", CurrentOwnerID: currentOwnerId}
+
+	_, err := ResumeJournal(context.Background(), store, ownerCfg)
+	assert.EqualError(t, err, "journal file '"+path+"' not found")
+
+	assert.NoError(t, NewJournal(store, &Config{Mode: "resource", CurrentOwnerID: currentOwnerId}).save(context.Background()))
+	_, err = ResumeJournal(context.Background(), store, ownerCfg)
+	assert.Error(t, err, "Journal of another mode")
+}
+
+func TestFileJournalStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	assert.NoError(t, os.WriteFile(path+".tmp", []byte("{}"), 0o644))
+	store := &FileJournalStore{Path: path}
+
+	assert.NoError(t, store.Save(context.Background(), []byte(`{"version":1}`)))
+	data, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":1}`, string(data))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Journal readable by the current user only")
+}
+
+func TestConfigMapJournalStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store, err := NewJournalStore("configmap:dns-tagger/journal", func() (kubernetes.Interface, error) {
+		return client, nil
+	})
+	assert.NoError(t, err)
+
+	data, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, data, "Missing ConfigMap is empty journal")
+
+	assert.NoError(t, store.Save(context.Background(), []byte(`{"version":1}`)))
+	assert.NoError(t, store.Save(context.Background(), []byte(`{"version":1,"entries":[]}`)))
+	data, err = store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":1,"entries":[]}`, string(data))
+	assert.Equal(t, "ConfigMap 'dns-tagger/journal'", store.(*ConfigMapJournalStore).String())
+}
+
+func TestNewJournalStore(t *testing.T) {
+	noClient := func() (kubernetes.Interface, error) {
+		panic("Kubernetes client is not needed")
+	}
+
+	store, err := NewJournalStore("/tmp/journal.json", noClient)
+	assert.NoError(t, err)
+	assert.Equal(t, &FileJournalStore{Path: "/tmp/journal.json"}, store)
+
+	_, err = NewJournalStore("configmap:journal", noClient)
+	assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/provider"
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// leaseResourcePrefix precedes run id in the resource of the lease record
+const leaseResourcePrefix = "lock/"
+
+// LeaseExpiresLabel keeps expiry of the lease record in RFC 3339 format
+const LeaseExpiresLabel = "lease-expires"
+
+// LockedError is returned when the zone is locked by an active lease of another run
+type LockedError struct {
+	Zone    string
+	Holder  string
+	RunID   string
+	Expires time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("zone '%s' is locked by '%s' run '%s' until %s", e.Zone, e.Holder, e.RunID, e.Expires.Format(time.RFC3339))
+}
+
+// Lock keeps lease TXT record in every zone while the run changes registry records, so runs started from
+// different clusters don't change the same records. Lease is a registry record named after the zone with holder
+// as owner, run id in resource and expiry label, it is created and deleted through the provider like any registry record
+type Lock struct {
+	provider provider.Provider
+	matcher  *registry.Matcher
+	name     string
+	holder   string
+	runID    string
+	ttl      time.Duration
+	now      func() time.Time
+
+	leases []*ZoneRecord
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewLock creates lock of the run with random run id, holder defaults to the host name
+func NewLock(cfg *Config, provider provider.Provider) *Lock {
+	holder := cfg.LockHolder
+	if holder == "" {
+		holder, _ = os.Hostname()
+	}
+	if holder == "" {
+		holder = "dns-tagger"
+	}
+	return &Lock{
+		provider: provider,
+		matcher:  cfg.Matcher(),
+		name:     cfg.LockName,
+		holder:   holder,
+		runID:    newRunID(),
+		ttl:      cfg.LockTTL,
+		now:      time.Now,
+	}
+}
+
+// RunID identifies the run in the lease records
+func (l *Lock) RunID() string {
+	return l.runID
+}
+
+// Acquire deletes expired leases and creates lease of the run in every zone. Zones are read again afterwards,
+// so runs that created leases at the same time both give up. Changes must be planned from the returned zones,
+// zones read before the lock may be changed by the previous run. Returned context is canceled when the lease
+// can't be renewed. Leases are renewed every third of the lease duration until Release
+func (l *Lock) Acquire(ctx context.Context, zones []*registry.Zone) (context.Context, []*registry.Zone, error) {
+	for _, zone := range zones {
+		if err := l.checkLeases(ctx, zone, true); err != nil {
+			return nil, nil, l.abandon(err)
+		}
+		lease := &registry.Record{Name: l.leaseName(zone), Owner: l.holder, Resource: leaseResourcePrefix + l.runID}
+		lease.SetLabel(LeaseExpiresLabel, l.expiry())
+		log.Infof("Locking zone '%s' with lease '%s'", zone.Name, lease)
+		if _, err := l.provider.CreateRegistryRecord(ctx, zone, lease); err != nil {
+			return nil, nil, l.abandon(fmt.Errorf("failed to create lease in zone '%s': %w", zone.Name, err))
+		}
+		l.mu.Lock()
+		l.leases = append(l.leases, &ZoneRecord{Zone: zone, Record: lease})
+		l.mu.Unlock()
+	}
+
+	currentZones, err := l.provider.ReadZones(ctx, l.matcher)
+	if err != nil {
+		return nil, nil, l.abandon(err)
+	}
+	for _, zone := range currentZones {
+		if err := l.checkLeases(ctx, zone, false); err != nil {
+			return nil, nil, l.abandon(err)
+		}
+	}
+
+	lockedCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.keepAlive(lockedCtx)
+	return lockedCtx, currentZones, nil
+}
+
+// Renew extends leases of the run. Error wrapping provider.ErrRecordChanged means the lease was taken over
+func (l *Lock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expiry := l.expiry()
+	for _, lease := range l.leases {
+		renewed := lease.Record.NewRecord(l.holder, lease.Record.Resource)
+		renewed.SetLabel(LeaseExpiresLabel, expiry)
+		if _, err := provider.UpdateRegistryRecordIf(ctx, l.provider, lease.Zone, lease.Record, renewed); err != nil {
+			return fmt.Errorf("failed to renew lease in zone '%s': %w", lease.Zone.Name, err)
+		}
+		lease.Record = renewed
+	}
+	log.Debugf("Renewed lock leases until %s", expiry)
+	return nil
+}
+
+// Release stops renewal and deletes leases of the run. It is safe to call several times
+func (l *Lock) Release(ctx context.Context) error {
+	if l.stop != nil {
+		select {
+		case <-l.stop:
+		default:
+			close(l.stop)
+		}
+		<-l.done
+		l.cancel()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for _, lease := range l.leases {
+		log.Infof("Unlocking zone '%s'", lease.Zone.Name)
+		if _, err := l.provider.DeleteRegistryRecord(ctx, lease.Zone, lease.Record); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete lease in zone '%s': %w", lease.Zone.Name, err))
+		}
+	}
+	l.leases = nil
+	return errors.Join(errs...)
+}
+
+// keepAlive renews leases until Release. Run is canceled when the lease was taken over or has expired
+func (l *Lock) keepAlive(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	expires := l.now().Add(l.ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := l.Renew(ctx)
+		switch {
+		case err == nil:
+			expires = l.now().Add(l.ttl)
+		case errors.Is(err, provider.ErrRecordChanged) || l.now().After(expires):
+			log.Errorf("Lock lost, stopping the run: %s", err)
+			// Lease may be already replaced by another run under the same registry key, it must not be deleted
+			l.mu.Lock()
+			l.leases = nil
+			l.mu.Unlock()
+			l.cancel()
+			return
+		default:
+			log.Warnf("Lock renewal will be retried: %s", err)
+		}
+	}
+}
+
+// checkLeases returns LockedError when the zone has active lease of another run, expired leases are deleted on request
+func (l *Lock) checkLeases(ctx context.Context, zone *registry.Zone, deleteExpired bool) error {
+	for _, record := range zone.RegistryRecords {
+		if record.Name != l.leaseName(zone) || l.isOwnLease(record) {
+			continue
+		}
+		expires, err := time.Parse(time.RFC3339, record.Labels[LeaseExpiresLabel])
+		if err == nil && l.now().Before(expires) {
+			return &LockedError{Zone: string(zone.Name), Holder: record.Owner, RunID: strings.TrimPrefix(record.Resource, leaseResourcePrefix), Expires: expires}
+		}
+		if !deleteExpired {
+			continue
+		}
+		log.Infof("Deleting expired lease '%s'", record)
+		if _, err := l.provider.DeleteRegistryRecord(ctx, zone, record); err != nil {
+			return fmt.Errorf("failed to delete expired lease in zone '%s': %w", zone.Name, err)
+		}
+	}
+	return nil
+}
+
+// abandon deletes leases created before the error
+func (l *Lock) abandon(err error) error {
+	if releaseErr := l.Release(context.Background()); releaseErr != nil {
+		return errors.Join(err, releaseErr)
+	}
+	return err
+}
+
+// IsLease checks whether the registry record is a lease of some run. Leases don't own endpoints, so they are never
+// listed as orphans, selected for owner changes or captured in snapshots
+func IsLease(record *registry.Record) bool {
+	_, ok := record.Labels[LeaseExpiresLabel]
+	return ok && strings.HasPrefix(record.Resource, leaseResourcePrefix)
+}
+
+func (l *Lock) isOwnLease(record *registry.Record) bool {
+	return record.Owner == l.holder && record.Resource == leaseResourcePrefix+l.runID
+}
+
+func (l *Lock) leaseName(zone *registry.Zone) registry.Hostname {
+	return registry.NewHostname(l.name + "." + string(zone.Name))
+}
+
+func (l *Lock) expiry() string {
+	return l.now().Add(l.ttl).UTC().Format(time.RFC3339)
+}
+
+func newRunID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
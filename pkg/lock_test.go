@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/provider"
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryProvider keeps registry records of the zones in memory
+type memoryProvider struct {
+	records map[registry.Hostname][]*registry.Record
+	// onRead is called before zones are read
+	onRead func()
+	mu     sync.Mutex
+}
+
+func newMemoryProvider(zoneNames ...string) *memoryProvider {
+	p := &memoryProvider{records: make(map[registry.Hostname][]*registry.Record)}
+	for _, name := range zoneNames {
+		p.records[registry.NewHostname(name)] = make([]*registry.Record, 0)
+	}
+	return p
+}
+
+func (p *memoryProvider) Whoami(_ context.Context) string {
+	return "Memory"
+}
+
+func (p *memoryProvider) ReadZones(_ context.Context, _ *registry.Matcher) ([]*registry.Zone, error) {
+	if p.onRead != nil {
+		p.onRead()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zones := make([]*registry.Zone, 0)
+	for name, records := range p.records {
+		zone := registry.NewZone(string(name))
+		for _, record := range records {
+			zone.AddRegistryRecord(record.NewRecord(record.Owner, record.Resource))
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+func (p *memoryProvider) UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	return p.UpdateRegistryRecordIf(ctx, zone, record, record)
+}
+
+func (p *memoryProvider) UpdateRegistryRecordIf(_ context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, current := range p.records[zone.Name] {
+		if current.Name == expected.Name && provider.IsRecordUnchanged(expected, current) {
+			p.records[zone.Name][i] = record.NewRecord(record.Owner, record.Resource)
+			return 1, nil
+		}
+	}
+	return 0, provider.ErrRecordChanged
+}
+
+func (p *memoryProvider) CreateRegistryRecord(_ context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records[zone.Name] = append(p.records[zone.Name], record.NewRecord(record.Owner, record.Resource))
+	return 1, nil
+}
+
+func (p *memoryProvider) DeleteRegistryRecord(_ context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, current := range p.records[zone.Name] {
+		if current.Name == record.Name && current.Owner == record.Owner && current.Resource == record.Resource {
+			p.records[zone.Name] = append(p.records[zone.Name][:i], p.records[zone.Name][i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (p *memoryProvider) zoneRecords(zone string) []*registry.Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*registry.Record{}, p.records[registry.Hostname(zone)]...)
+}
+
+func newTestLock(p provider.Provider, holder string, ttl time.Duration) *Lock {
+	return NewLock(&Config{LockName: "dns-tagger-lock", LockHolder: holder, LockTTL: ttl}, p)
+}
+
+func foreignLease(holder string, expires time.Time) *registry.Record {
+	lease := &registry.Record{Name: "dns-tagger-lock.dummy.host", Owner: holder, Resource: leaseResourcePrefix + "other-run"}
+	lease.SetLabel(LeaseExpiresLabel, expires.UTC().Format(time.RFC3339))
+	return lease
+}
+
+func TestLock_AcquireRelease(t *testing.T) {
+	memory := newMemoryProvider("dummy.host", "other.host")
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	lock := newTestLock(memory, "cluster-2-job", time.Minute)
+
+	ctx, lockedZones, err := lock.Acquire(context.Background(), zones)
+
+	assert.NoError(t, err)
+	assert.Len(t, lockedZones, 2, "Zones read under the lock returned")
+	for _, zone := range []string{"dummy.host", "other.host"} {
+		leases := memory.zoneRecords(zone)
+		assert.Len(t, leases, 1, "Lease created in every zone")
+		assert.Equal(t, registry.Hostname("dns-tagger-lock."+zone), leases[0].Name)
+		assert.Equal(t, "cluster-2-job", leases[0].Owner)
+		assert.Equal(t, leaseResourcePrefix+lock.RunID(), leases[0].Resource)
+		assert.NotEmpty(t, leases[0].Labels[LeaseExpiresLabel])
+	}
+
+	assert.NoError(t, lock.Release(context.Background()))
+	assert.Empty(t, memory.zoneRecords("dummy.host"), "Lease deleted on release")
+	assert.Error(t, ctx.Err(), "Run context canceled on release")
+	assert.NoError(t, lock.Release(context.Background()), "Second release is no-op")
+}
+
+func TestLock_Acquire_CurrentZones(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	memory.onRead = func() {
+		memory.onRead = nil
+		_, _ = memory.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), &registry.Record{Name: "edns-webserver.dummy.host", Owner: "cluster-1"})
+	}
+	lock := newTestLock(memory, "cluster-2-job", time.Minute)
+
+	_, lockedZones, err := lock.Acquire(context.Background(), zones)
+
+	assert.NoError(t, err)
+	var names []registry.Hostname
+	for _, record := range lockedZones[0].RegistryRecords {
+		names = append(names, record.Name)
+	}
+	assert.Contains(t, names, registry.Hostname("edns-webserver.dummy.host"), "Record written by the previous run before the lock is read")
+	assert.NoError(t, lock.Release(context.Background()))
+}
+
+func TestLock_Acquire_ActiveLease(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	_, _ = memory.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), foreignLease("cluster-1-job", time.Now().Add(time.Minute)))
+	zones, _ := memory.ReadZones(context.Background(), nil)
+
+	_, _, err := newTestLock(memory, "cluster-2-job", time.Minute).Acquire(context.Background(), zones)
+
+	var locked *LockedError
+	assert.ErrorAs(t, err, &locked)
+	assert.Equal(t, "cluster-1-job", locked.Holder)
+	assert.Equal(t, "other-run", locked.RunID)
+	assert.Len(t, memory.zoneRecords("dummy.host"), 1, "Active lease kept")
+}
+
+func TestLock_Acquire_ExpiredLease(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	_, _ = memory.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), foreignLease("cluster-1-job", time.Now().Add(-time.Minute)))
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	lock := newTestLock(memory, "cluster-2-job", time.Minute)
+
+	_, _, err := lock.Acquire(context.Background(), zones)
+
+	assert.NoError(t, err)
+	leases := memory.zoneRecords("dummy.host")
+	assert.Len(t, leases, 1, "Expired lease replaced")
+	assert.Equal(t, "cluster-2-job", leases[0].Owner)
+	assert.NoError(t, lock.Release(context.Background()))
+}
+
+func TestLock_Acquire_ConcurrentLease(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	memory.onRead = func() {
+		memory.onRead = nil
+		_, _ = memory.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), foreignLease("cluster-1-job", time.Now().Add(time.Minute)))
+	}
+
+	_, _, err := newTestLock(memory, "cluster-2-job", time.Minute).Acquire(context.Background(), zones)
+
+	var locked *LockedError
+	assert.ErrorAs(t, err, &locked, "Lease created by another run at the same time")
+	leases := memory.zoneRecords("dummy.host")
+	assert.Len(t, leases, 1, "Own lease deleted")
+	assert.Equal(t, "cluster-1-job", leases[0].Owner)
+}
+
+func TestLock_Renew(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	lock := newTestLock(memory, "cluster-2-job", time.Minute)
+	var elapsed atomic.Int64
+	lock.now = func() time.Time { return time.Now().Add(time.Duration(elapsed.Load())) }
+	_, _, err := lock.Acquire(context.Background(), zones)
+	assert.NoError(t, err)
+
+	elapsed.Store(int64(time.Hour))
+	assert.NoError(t, lock.Renew(context.Background()))
+	expires, _ := time.Parse(time.RFC3339, memory.zoneRecords("dummy.host")[0].Labels[LeaseExpiresLabel])
+	assert.True(t, expires.After(time.Now().Add(time.Hour)), "Lease extended")
+
+	memory.mu.Lock()
+	memory.records["dummy.host"][0].Owner = "cluster-1-job"
+	memory.mu.Unlock()
+	assert.ErrorIs(t, lock.Renew(context.Background()), provider.ErrRecordChanged, "Lease taken over by another run")
+	assert.NoError(t, lock.Release(context.Background()))
+}
+
+func TestLock_KeepAlive_LeaseLost(t *testing.T) {
+	memory := newMemoryProvider("dummy.host")
+	zones, _ := memory.ReadZones(context.Background(), nil)
+	lock := newTestLock(memory, "cluster-2-job", 30*time.Millisecond)
+	ctx, _, err := lock.Acquire(context.Background(), zones)
+	assert.NoError(t, err)
+
+	memory.mu.Lock()
+	memory.records["dummy.host"][0] = foreignLease("cluster-1-job", time.Now().Add(time.Minute))
+	memory.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Run context not canceled after lease was lost")
+	}
+	assert.NoError(t, lock.Release(context.Background()))
+	assert.Equal(t, "cluster-1-job", memory.zoneRecords("dummy.host")[0].Owner, "Lease of another run kept on release")
+}
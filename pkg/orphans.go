@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// FindOrphanRecords returns registry records without hosts except lock leases. When owner ids are configured
+// only records of those owners are returned
+func (s *Selector) FindOrphanRecords(zones []*registry.Zone) []*ZoneRecord {
+	orphans := make([]*ZoneRecord, 0)
+	for _, zone := range zones {
+		for _, record := range zone.OrphanRecords(s.cfg.Matcher()) {
+			if IsLease(record) {
+				continue
+			}
+			if len(s.cfg.OrphanOwnerIDs) > 0 && !containsString(s.cfg.OrphanOwnerIDs, record.Owner) {
+				continue
+			}
+			orphans = append(orphans, &ZoneRecord{Zone: zone, Record: record})
+		}
+	}
+	return orphans
+}
+
+// DeleteOrphanRecords deletes orphaned registry records, owners are checked again to guard against unfiltered list
+func (s *Selector) DeleteOrphanRecords(ctx context.Context, orphans []*ZoneRecord) (deletedRecords int, err error) {
+	for _, orphan := range orphans {
+		if !containsString(s.cfg.OrphanOwnerIDs, orphan.Record.Owner) {
+			log.Warnf("Orphaned registry record not deleted. Owner '%s' is not selected. '%s'", orphan.Record.Owner, orphan.Record)
+			continue
+		}
+
+		log.Infof("Deleting orphaned registry record '%s'", orphan.Record)
+		deletes, err := s.provider.DeleteRegistryRecord(ctx, orphan.Zone, orphan.Record)
+		deletedRecords += deletes
+		if err != nil {
+			return deletedRecords, err
+		}
+	}
+	return deletedRecords, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func createOrphansZone() *registry.Zone {
+	return buildTestZone(
+		testRecord{host: testEndpointHost, name: "registry1-" + testEndpointHost, owner: "cluster-1", resource: testEndpointResource},
+		testRecord{host: testEndpointHost, name: "registry1-cname-" + testEndpointHost, owner: "cluster-1", resource: testEndpointResource},
+		testRecord{name: "registry1-deleted.dummy.host", owner: "cluster-1", resource: testEndpointResource},
+		testRecord{name: "registry1-other.dummy.host", owner: "cluster-3", resource: testEndpointResource},
+	)
+}
+
+func TestSelector_FindOrphanRecords_Lease(t *testing.T) {
+	zone := registry.NewZone("dummy.host")
+	zone.AddRegistryRecord(foreignLease("cluster-3-job", time.Now().Add(time.Minute)))
+
+	orphans := NewSelector(&Config{OrphanOwnerIDs: []string{"cluster-3-job"}}, &mockProvider{}).FindOrphanRecords([]*registry.Zone{zone})
+	assert.Empty(t, orphans, "Lease is not orphaned")
+}
+
+func TestSelector_FindOrphanRecords(t *testing.T) {
+	zone := createOrphansZone()
+
+	orphans := NewSelector(&Config{}, &mockProvider{}).FindOrphanRecords([]*registry.Zone{zone})
+	assert.Equal(t, []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[2]}, {Zone: zone, Record: zone.RegistryRecords[3]}}, orphans)
+
+	orphans = NewSelector(&Config{OrphanOwnerIDs: []string{"cluster-3"}}, &mockProvider{}).FindOrphanRecords([]*registry.Zone{zone})
+	assert.Equal(t, []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[3]}}, orphans, "Filtered by owner")
+}
+
+func TestSelector_DeleteOrphanRecords(t *testing.T) {
+	testProvider := &mockProvider{}
+	zone := createOrphansZone()
+	selector := NewSelector(&Config{OrphanOwnerIDs: []string{"cluster-1"}}, testProvider)
+	testProvider.On("DeleteRegistryRecord", context.Background(), zone, zone.RegistryRecords[2]).Return(1, nil)
+
+	orphans := []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[2]}, {Zone: zone, Record: zone.RegistryRecords[3]}}
+	deletes, err := selector.DeleteOrphanRecords(context.Background(), orphans)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletes, "Only records of selected owners deleted")
+	testProvider.AssertNumberOfCalls(t, "DeleteRegistryRecord", 1)
+}
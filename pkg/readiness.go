@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// ReadinessHost compares host owned by the old cluster with resources serving it in both clusters
+type ReadinessHost struct {
+	Zone string `json:"zone"`
+	Name string `json:"name"`
+	// Owner and Resource of canonical registry record
+	Owner        string   `json:"owner"`
+	Resource     string   `json:"resource"`
+	OldResources []string `json:"oldResources"`
+	NewResources []string `json:"newResources"`
+}
+
+// Readiness lists hosts owned by previous owner ids that block or would change during migration to the new cluster
+type Readiness struct {
+	// Missing hosts have no source in the new cluster
+	Missing []*ReadinessHost `json:"missing"`
+	// ResourceMismatches hosts are served by the new cluster with other resources than by the old cluster
+	ResourceMismatches []*ReadinessHost `json:"resourceMismatches"`
+	// Claimable hosts are served by the new cluster and would be claimed in owner mode
+	Claimable []*ReadinessHost `json:"claimable"`
+}
+
+// Ready reports whether every host owned by the old cluster is served by the new cluster with the same resource
+func (r *Readiness) Ready() bool {
+	return len(r.Missing) == 0 && len(r.ResourceMismatches) == 0
+}
+
+// CheckReadiness compares endpoints of the old and new clusters against hosts owned by previous owner ids.
+// Expected resource of the host is the one serving it in the old cluster or the registry record resource
+// when the old cluster does not serve it anymore
+func (s *Selector) CheckReadiness(oldEndpoints []*registry.Endpoint, newEndpoints []*registry.Endpoint, zones []*registry.Zone) *Readiness {
+	oldResources := endpointResources(oldEndpoints)
+	newResources := endpointResources(newEndpoints)
+
+	readiness := &Readiness{
+		Missing:            make([]*ReadinessHost, 0),
+		ResourceMismatches: make([]*ReadinessHost, 0),
+		Claimable:          make([]*ReadinessHost, 0),
+	}
+	for _, zone := range zones {
+		// Record sets of the same host are checked once
+		processedHosts := make(map[registry.Hostname]bool)
+		for _, host := range zone.Hosts {
+			if processedHosts[host.Name] || !host.IsManaged() {
+				continue
+			}
+			canonical := host.RegistryRecords[0]
+			if conflict := registry.AnalyzeRegistryRecords(host); conflict != nil {
+				canonical = conflict.Canonical
+			}
+			if !s.isAllowedOwner(canonical.Owner) {
+				continue
+			}
+			processedHosts[host.Name] = true
+
+			readinessHost := &ReadinessHost{
+				Zone:         string(zone.Name),
+				Name:         string(host.Name),
+				Owner:        canonical.Owner,
+				Resource:     canonical.Resource,
+				OldResources: oldResources[host.Name],
+				NewResources: newResources[host.Name],
+			}
+			if len(readinessHost.NewResources) == 0 {
+				readiness.Missing = append(readiness.Missing, readinessHost)
+				continue
+			}
+			readiness.Claimable = append(readiness.Claimable, readinessHost)
+
+			expectedResources := readinessHost.OldResources
+			if len(expectedResources) == 0 {
+				expectedResources = []string{canonical.Resource}
+			}
+			if !containsAny(readinessHost.NewResources, expectedResources) {
+				readiness.ResourceMismatches = append(readiness.ResourceMismatches, readinessHost)
+			}
+		}
+	}
+	return readiness
+}
+
+// Write renders readiness report in table or json format
+func (r *Readiness) Write(w io.Writer, format string) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case OutputTable:
+		return r.writeTable(w)
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+func (r *Readiness) writeTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeReadinessHosts(table, "Hosts without source in the new cluster:", r.Missing)
+	writeReadinessHosts(table, "Hosts with resource mismatch:", r.ResourceMismatches)
+	writeReadinessHosts(table, "Hosts that would be claimed:", r.Claimable)
+	fmt.Fprintf(table, "Missing: %d, resource mismatches: %d, claimable: %d\n", len(r.Missing), len(r.ResourceMismatches), len(r.Claimable))
+	return table.Flush()
+}
+
+func writeReadinessHosts(w io.Writer, title string, hosts []*ReadinessHost) {
+	if len(hosts) == 0 {
+		return
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, "  HOST\tOWNER\tRESOURCE\tOLD RESOURCES\tNEW RESOURCES")
+	for _, host := range hosts {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", host.Name, host.Owner, host.Resource, formatResources(host.OldResources), formatResources(host.NewResources))
+	}
+	fmt.Fprintln(w)
+}
+
+func formatResources(resources []string) string {
+	if len(resources) == 0 {
+		return "-"
+	}
+	return strings.Join(resources, ",")
+}
+
+// endpointResources groups sorted unique resources of the endpoints by host
+func endpointResources(endpoints []*registry.Endpoint) map[registry.Hostname][]string {
+	resources := make(map[registry.Hostname][]string)
+	for _, endpoint := range endpoints {
+		if !containsString(resources[endpoint.Host], endpoint.Resource) {
+			resources[endpoint.Host] = append(resources[endpoint.Host], endpoint.Resource)
+		}
+	}
+	for _, hostResources := range resources {
+		sort.Strings(hostResources)
+	}
+	return resources
+}
+
+func containsAny(values []string, expected []string) bool {
+	for _, value := range expected {
+		if containsString(values, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func createReadinessZone() *registry.Zone {
+	zone := registry.NewZone("dummy.host")
+	for _, hostResource := range [][]string{
+		{"webserver.dummy.host", "cluster-1", "ingress/test/webserver"},
+		{"api.dummy.host", "cluster-1", "ingress/test/api"},
+		{"admin.dummy.host", "cluster-1", "ingress/test/admin"},
+		{"docs.dummy.host", "cluster-1", "ingress/test/docs"},
+		{"other.dummy.host", "cluster-0", "ingress/test/other"},
+	} {
+		for _, recordType := range []string{"A", "AAAA"} {
+			host := registry.NewHost(hostResource[0], recordType, "127.0.0.1")
+			host.AddRegistryRecord(&registry.Record{Name: "edns-" + registry.Hostname(hostResource[0]), Owner: hostResource[1], Resource: hostResource[2]})
+			zone.AddHost(host)
+		}
+	}
+	zone.AddHost(registry.NewHost("mail.dummy.host", "A", "127.0.0.2"))
+	return zone
+}
+
+func TestSelector_CheckReadiness(t *testing.T) {
+	selector := NewSelector(cfg, &mockProvider{})
+	oldEndpoints := []*registry.Endpoint{
+		registry.NewEndpoint("webserver.dummy.host", "ingress/test/webserver", "ingress"),
+		registry.NewEndpoint("api.dummy.host", "ingress/test/api", "ingress"),
+		registry.NewEndpoint("admin.dummy.host", "ingress/test/admin", "ingress"),
+	}
+	newEndpoints := []*registry.Endpoint{
+		registry.NewEndpoint("webserver.dummy.host", "ingress/test/webserver", "ingress"),
+		registry.NewEndpoint("webserver.dummy.host", "istio-virtualservice/test/webserver", "istio-virtualservice"),
+		registry.NewEndpoint("api.dummy.host", "ingress/test/api-v2", "ingress"),
+		registry.NewEndpoint("docs.dummy.host", "ingress/test/documentation", "ingress"),
+		registry.NewEndpoint("other.dummy.host", "ingress/test/other", "ingress"),
+	}
+
+	readiness := selector.CheckReadiness(oldEndpoints, newEndpoints, []*registry.Zone{createReadinessZone()})
+
+	hostNames := func(hosts []*ReadinessHost) []string {
+		names := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			names = append(names, host.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"admin.dummy.host"}, hostNames(readiness.Missing), "Hosts of old owner without new source")
+	assert.Equal(t, []string{"api.dummy.host", "docs.dummy.host"}, hostNames(readiness.ResourceMismatches), "Resource compared to old cluster or registry record")
+	assert.Equal(t, []string{"webserver.dummy.host", "api.dummy.host", "docs.dummy.host"}, hostNames(readiness.Claimable), "Record sets of the same host reported once")
+	assert.Equal(t, []string{"ingress/test/webserver", "istio-virtualservice/test/webserver"}, readiness.Claimable[0].NewResources)
+	assert.False(t, readiness.Ready())
+}
+
+func TestReadiness_Write(t *testing.T) {
+	readiness := &Readiness{
+		Missing:            []*ReadinessHost{{Zone: "dummy.host", Name: "admin.dummy.host", Owner: "cluster-1", Resource: "ingress/test/admin", OldResources: []string{"ingress/test/admin"}}},
+		ResourceMismatches: []*ReadinessHost{},
+		Claimable:          []*ReadinessHost{},
+	}
+
+	var tableOutput bytes.Buffer
+	assert.NoError(t, readiness.Write(&tableOutput, OutputTable))
+	assert.Contains(t, tableOutput.String(), "Hosts without source in the new cluster:")
+	assert.Contains(t, tableOutput.String(), "admin.dummy.host  cluster-1  ingress/test/admin  ingress/test/admin  -")
+	assert.Contains(t, tableOutput.String(), "Missing: 1, resource mismatches: 0, claimable: 0")
+
+	var jsonOutput bytes.Buffer
+	assert.NoError(t, readiness.Write(&jsonOutput, OutputJSON))
+	assert.Contains(t, jsonOutput.String(), `"missing": [`)
+
+	assert.Error(t, readiness.Write(&tableOutput, OutputCSV))
+}
@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// FindReassignRecords returns registry records owned by the reassigned owner id matching host and resource filters.
+// Records without hosts are selected only when host filter is not configured
+func (s *Selector) FindReassignRecords(zones []*registry.Zone) []*ZoneRecord {
+	return s.selectZoneRecords(zones, func(record *registry.Record) bool {
+		return record.Owner == s.cfg.FromOwnerID
+	})
+}
+
+// ReassignRecords sets new owner id on registry records keeping their resources
+func (s *Selector) ReassignRecords(ctx context.Context, records []*ZoneRecord) (updatedRecords int, err error) {
+	for _, selectedRecord := range records {
+		log.Infof("Reassigning owner info for '%s' to '%s'", selectedRecord.Record, s.cfg.ToOwnerID)
+		updatedRecord := selectedRecord.Record.NewRecord(s.cfg.ToOwnerID, selectedRecord.Record.Resource)
+		updates, err := s.updateRecord(ctx, selectedRecord.Zone, selectedRecord.Record, updatedRecord)
+		updatedRecords += updates
+		if err != nil {
+			return updatedRecords, err
+		}
+	}
+	return updatedRecords, nil
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/provider"
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createReassignZone() *registry.Zone {
+	return buildTestZone(
+		testRecord{host: "webserver.dummy.host", recordType: "A", name: "edns-webserver.dummy.host", owner: "cluster-1", resource: "ingress/production/webserver"},
+		testRecord{host: "api.dummy.host", recordType: "A", name: "edns-api.dummy.host", owner: "cluster-1", resource: "virtualservice/production/api"},
+		testRecord{host: "admin.dummy.host", recordType: "A", name: "edns-admin.dummy.host", owner: "cluster-3", resource: "ingress/production/admin"},
+		testRecord{name: "edns-deleted.dummy.host", owner: "cluster-1", resource: "ingress/production/deleted"},
+	)
+}
+
+func TestSelector_FindReassignRecords(t *testing.T) {
+	zone := createReassignZone()
+	tests := []struct {
+		name     string
+		regex    *regexp.Regexp
+		pattern  string
+		expected []*registry.Record
+	}{
+		{name: "All records of owner", expected: []*registry.Record{zone.RegistryRecords[0], zone.RegistryRecords[1], zone.RegistryRecords[3]}},
+		{name: "Host regex", regex: regexp.MustCompile(`^api\.`), expected: []*registry.Record{zone.RegistryRecords[1]}},
+		{name: "Resource pattern", pattern: "ingress/production/*", expected: []*registry.Record{zone.RegistryRecords[0], zone.RegistryRecords[3]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := NewSelector(&Config{FromOwnerID: "cluster-1", ToOwnerID: "cluster-2", HostRegex: tt.regex, ResourcePattern: tt.pattern}, &mockProvider{})
+
+			var records []*registry.Record
+			for _, selected := range selector.FindReassignRecords([]*registry.Zone{zone}) {
+				records = append(records, selected.Record)
+			}
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestSelector_FindReassignRecords_Lease(t *testing.T) {
+	zone := createReassignZone()
+	zone.AddRegistryRecord(foreignLease("cluster-1", time.Now().Add(time.Minute)))
+	selector := NewSelector(&Config{FromOwnerID: "cluster-1", ToOwnerID: "cluster-2"}, &mockProvider{})
+
+	records := selector.FindReassignRecords([]*registry.Zone{zone})
+	assert.Len(t, records, 3, "Lease of the owner is not reassigned")
+}
+
+func TestSelector_ReassignRecords(t *testing.T) {
+	testProvider := &mockProvider{}
+	zone := createReassignZone()
+	selector := NewSelector(&Config{FromOwnerID: "cluster-1", ToOwnerID: "cluster-2"}, testProvider)
+	testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.MatchedBy(func(record *registry.Record) bool {
+		return record.Owner == "cluster-2" && record.Resource == "ingress/production/webserver"
+	})).Return(1, nil)
+
+	updates, err := selector.ReassignRecords(context.Background(), []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[0]}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, "cluster-1", zone.RegistryRecords[0].Owner, "Read record is not modified")
+}
+
+func TestSelector_ReassignRecords_RecordChanged(t *testing.T) {
+	testProvider := &conditionalMockProvider{}
+	zone := createReassignZone()
+	selector := NewSelector(&Config{FromOwnerID: "cluster-1", ToOwnerID: "cluster-2"}, testProvider)
+	testProvider.On("UpdateRegistryRecordIf", context.Background(), zone, zone.RegistryRecords[0], mock.Anything).Return(0, fmt.Errorf("%w: test", provider.ErrRecordChanged))
+	testProvider.On("UpdateRegistryRecordIf", context.Background(), zone, zone.RegistryRecords[1], mock.Anything).Return(1, nil)
+	records := []*ZoneRecord{{Zone: zone, Record: zone.RegistryRecords[0]}, {Zone: zone, Record: zone.RegistryRecords[1]}}
+
+	updates, err := selector.ReassignRecords(context.Background(), records)
+
+	assert.NoError(t, err, "Rewritten record is skipped")
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, records[:1], selector.SkippedRecords(), "Rewritten record listed as skipped")
+}
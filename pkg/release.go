@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// FindStaleRecords returns registry records owned by current owner id whose hosts have no source endpoint,
+// i.e. records current cluster claims but no longer serves
+func (s *Selector) FindStaleRecords(endpoints []*registry.Endpoint, zones []*registry.Zone) []*ZoneRecord {
+	servedHosts := make(map[registry.Hostname]bool)
+	for _, endpoint := range endpoints {
+		servedHosts[endpoint.Host] = true
+	}
+
+	staleRecords := make([]*ZoneRecord, 0)
+	for _, zone := range zones {
+		var hostRecords []*registry.Record
+		for _, host := range zone.Hosts {
+			if !servedHosts[host.Name] {
+				hostRecords = append(hostRecords, host.RegistryRecords...)
+			}
+		}
+		for _, record := range registry.UniqueRecords(hostRecords) {
+			if s.isAlreadyOwned(record.Owner) {
+				staleRecords = append(staleRecords, &ZoneRecord{Zone: zone, Record: record})
+			}
+		}
+	}
+	return staleRecords
+}
+
+// ReleaseRecords hands registry records over to release owner id keeping their resources
+func (s *Selector) ReleaseRecords(ctx context.Context, records []*ZoneRecord) (updatedRecords int, err error) {
+	for _, staleRecord := range records {
+		if !s.isAlreadyOwned(staleRecord.Record.Owner) {
+			log.Warnf("Owner not released. Record is not owned by '%s'. '%s'", s.cfg.CurrentOwnerID, staleRecord.Record)
+			continue
+		}
+
+		log.Infof("Releasing owner info for '%s' to '%s'", staleRecord.Record, s.cfg.ReleaseOwnerID)
+		updatedRecord := staleRecord.Record.NewRecord(s.cfg.ReleaseOwnerID, staleRecord.Record.Resource)
+		updates, err := s.updateRecord(ctx, staleRecord.Zone, staleRecord.Record, updatedRecord)
+		updatedRecords += updates
+		if err != nil {
+			return updatedRecords, err
+		}
+	}
+	return updatedRecords, nil
+}
@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createReleaseZone() *registry.Zone {
+	return buildTestZone(
+		testRecord{host: testEndpointHost, name: "registry1-" + testEndpointHost, owner: currentOwnerId, resource: testEndpointResource},
+		testRecord{host: testEndpointHost, name: "registry1-cname-" + testEndpointHost, owner: currentOwnerId, resource: testEndpointResource},
+		testRecord{host: "api.dummy.host", recordType: "A", name: "registry1-api.dummy.host", owner: currentOwnerId, resource: testEndpointResource2},
+		testRecord{host: "api.dummy.host", recordType: "AAAA", name: "registry1-api.dummy.host", owner: currentOwnerId, resource: testEndpointResource2},
+		testRecord{host: "other.dummy.host", recordType: "A", name: "registry1-other.dummy.host", owner: "cluster-1", resource: testEndpointResource2},
+	)
+}
+
+func TestSelector_FindStaleRecords(t *testing.T) {
+	zone := createReleaseZone()
+	selector := NewSelector(cfg, &mockProvider{})
+	endpoints := []*registry.Endpoint{{Host: testEndpointHost, Resource: testEndpointResource}}
+
+	staleRecords := selector.FindStaleRecords(endpoints, []*registry.Zone{zone})
+
+	assert.Equal(t, []*ZoneRecord{{Zone: zone, Record: zone.Hosts[1].RegistryRecords[0]}}, staleRecords, "Only unserved records of current owner")
+}
+
+func TestSelector_ReleaseRecords(t *testing.T) {
+	testProvider := &mockProvider{}
+	zone := createReleaseZone()
+	selector := NewSelector(&Config{CurrentOwnerID: currentOwnerId, ReleaseOwnerID: "cluster-1"}, testProvider)
+	testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.MatchedBy(func(record *registry.Record) bool {
+		return record.Owner == "cluster-1" && record.Resource == testEndpointResource2
+	})).Return(1, nil)
+
+	records := []*ZoneRecord{{Zone: zone, Record: zone.Hosts[1].RegistryRecords[0]}, {Zone: zone, Record: zone.Hosts[3].RegistryRecords[0]}}
+	updates, err := selector.ReleaseRecords(context.Background(), records)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Only records of current owner released")
+	testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", 1)
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// Report summarizes the run so the operator can review what was processed
+type Report struct {
+	// ZoneEndpoints counts source endpoints assigned to every zone
+	ZoneEndpoints map[string]int
+	// UnmatchedEndpoints do not belong to any of the configured zones
+	UnmatchedEndpoints []*registry.Endpoint
+	// Conflicts lists hosts claimed by several source endpoints with different resources
+	Conflicts []*Conflict
+	// RecordConflicts lists hosts with registry records that disagree with each other or are duplicated
+	RecordConflicts []*registry.RecordConflict
+	// Results lists decisions made for every endpoint and registry record
+	Results []*Result
+
+	// mu guards report changes made by concurrent workers
+	mu sync.Mutex
+}
+
+func NewReport() *Report {
+	return &Report{ZoneEndpoints: make(map[string]int), UnmatchedEndpoints: make([]*registry.Endpoint, 0), Conflicts: make([]*Conflict, 0), RecordConflicts: make([]*registry.RecordConflict, 0), Results: make([]*Result, 0)}
+}
+
+// AddZoneEndpoint records zone selected for the endpoint, nil zone means endpoint is outside configured zones
+func (r *Report) AddZoneEndpoint(zone *registry.Zone, endpoint *registry.Endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if zone == nil {
+		r.UnmatchedEndpoints = append(r.UnmatchedEndpoints, endpoint)
+		return
+	}
+	r.ZoneEndpoints[string(zone.Name)]++
+}
+
+// AddConflict records host conflict between source endpoints, resolved or not
+func (r *Report) AddConflict(conflict *Conflict) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Conflicts = append(r.Conflicts, conflict)
+}
+
+// AddRecordConflict records host with conflicting registry records
+func (r *Report) AddRecordConflict(conflict *registry.RecordConflict) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.RecordConflicts = append(r.RecordConflicts, conflict)
+}
+
+// AddResult records decision made for the endpoint or its registry record
+func (r *Report) AddResult(result *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Results = append(r.Results, result)
+}
+
+// HasProblems reports whether any endpoint or registry record requires operator attention
+func (r *Report) HasProblems() bool {
+	for _, result := range r.Results {
+		if result.IsProblem() {
+			return true
+		}
+	}
+	return false
+}
+
+// UnresolvedConflicts returns conflicts of the hosts that were skipped
+func (r *Report) UnresolvedConflicts() []*Conflict {
+	unresolved := make([]*Conflict, 0)
+	for _, conflict := range r.Conflicts {
+		if conflict.Resolved == nil {
+			unresolved = append(unresolved, conflict)
+		}
+	}
+	return unresolved
+}
+
+// Log writes report summary with structured fields
+func (r *Report) Log() {
+	zones := make([]string, 0, len(r.ZoneEndpoints))
+	for zone := range r.ZoneEndpoints {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	for _, zone := range zones {
+		log.WithFields(log.Fields{"zone": zone, "endpoints": r.ZoneEndpoints[zone]}).Info("Zone summary")
+	}
+
+	if len(r.UnmatchedEndpoints) > 0 {
+		hosts := make([]string, 0, len(r.UnmatchedEndpoints))
+		for _, endpoint := range r.UnmatchedEndpoints {
+			hosts = append(hosts, string(endpoint.Host))
+		}
+		log.WithFields(log.Fields{"endpoints": len(r.UnmatchedEndpoints), "hosts": hosts}).Warn("Endpoints without DNS zone")
+	}
+
+	for _, conflict := range r.Conflicts {
+		resources := make([]string, 0, len(conflict.Endpoints))
+		for _, endpoint := range conflict.Endpoints {
+			resources = append(resources, endpoint.Resource)
+		}
+		fields := log.Fields{"host": string(conflict.Host), "resources": resources, "reason": conflict.Reason}
+		if conflict.Resolved == nil {
+			log.WithFields(fields).Warn("Unresolved host conflict")
+		} else {
+			fields["resolved"] = conflict.Resolved.Resource
+			log.WithFields(fields).Info("Host conflict resolved")
+		}
+	}
+
+	for _, conflict := range r.RecordConflicts {
+		records := make([]string, 0, len(conflict.Host.RegistryRecords))
+		for _, record := range conflict.Host.RegistryRecords {
+			records = append(records, record.String())
+		}
+		log.WithFields(log.Fields{
+			"host":             string(conflict.Host.Name),
+			"type":             conflict.Host.RecordType,
+			"records":          records,
+			"ownerMismatch":    conflict.OwnerMismatch,
+			"resourceMismatch": conflict.ResourceMismatch,
+			"mixedFormats":     conflict.MixedFormats,
+			"duplicates":       len(conflict.Duplicates),
+		}).Warn("Conflicting registry records")
+	}
+}
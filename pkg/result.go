@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// OutputMarkdown renders report as markdown table, e.g. for pull request or incident notes
+const OutputMarkdown = "markdown"
+
+// Outcomes of endpoint and registry record processing
+const (
+	// OutcomeUpdated registry record was updated
+	OutcomeUpdated = "updated"
+	// OutcomeCreated registry record was created for unmanaged host
+	OutcomeCreated = "created"
+	// OutcomeDeleted redundant registry record was deleted
+	OutcomeDeleted = "deleted"
+	// OutcomeUpToDate registry record already has expected owner and resource
+	OutcomeUpToDate = "up-to-date"
+	// OutcomeOwnerNotAllowed registry record owner is not allowed to be changed
+	OutcomeOwnerNotAllowed = "owner-not-allowed"
+	// OutcomeNoZone endpoint does not belong to any of the configured zones
+	OutcomeNoZone = "no-zone"
+	// OutcomeNoHost zone has no host record of the endpoint
+	OutcomeNoHost = "no-host"
+	// OutcomeNoRegistry host has no registry records
+	OutcomeNoRegistry = "no-registry"
+	// OutcomeConflict endpoint host is claimed by other endpoints with different resources and conflict is not resolved
+	OutcomeConflict = "conflict"
+	// OutcomeChanged registry record was changed outside of the run since the change was planned
+	OutcomeChanged = "changed"
+	// OutcomeFailed provider failed to change registry record
+	OutcomeFailed = "failed"
+)
+
+// outcomeOrder sorts outcome counts in reports
+var outcomeOrder = []string{OutcomeUpdated, OutcomeCreated, OutcomeDeleted, OutcomeUpToDate, OutcomeOwnerNotAllowed, OutcomeNoZone, OutcomeNoHost, OutcomeNoRegistry, OutcomeConflict, OutcomeChanged, OutcomeFailed}
+
+// problemOutcomes require operator attention
+var problemOutcomes = map[string]bool{
+	OutcomeOwnerNotAllowed: true,
+	OutcomeNoZone:          true,
+	OutcomeNoHost:          true,
+	OutcomeNoRegistry:      true,
+	OutcomeConflict:        true,
+	OutcomeChanged:         true,
+	OutcomeFailed:          true,
+}
+
+// Result is the decision made for the endpoint or one of its registry records
+type Result struct {
+	Host string `json:"host"`
+	// RecordType of the host record set or new format registry record, empty for legacy records
+	RecordType string `json:"recordType,omitempty"`
+	Resource   string `json:"resource"`
+	Zone       string `json:"zone,omitempty"`
+	Record     string `json:"record,omitempty"`
+	// Owner of the registry record before the change
+	Owner   string `json:"owner,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewResult describes outcome of the endpoint, zone and record are optional
+func NewResult(endpoint *registry.Endpoint, zone *registry.Zone, record *registry.Record, outcome string, err error) *Result {
+	result := &Result{Host: string(endpoint.Host), Resource: endpoint.Resource, Outcome: outcome}
+	if zone != nil {
+		result.Zone = string(zone.Name)
+	}
+	if record != nil {
+		result.Record = string(record.Name)
+		result.RecordType = record.RecordType
+		result.Owner = record.Owner
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// IsProblem reports whether the outcome requires operator attention
+func (r *Result) IsProblem() bool {
+	return problemOutcomes[r.Outcome]
+}
+
+// WriteResults renders results and count of every outcome in table, json or markdown format
+func (r *Report) WriteResults(w io.Writer, format string) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Results  []*Result      `json:"results"`
+			Outcomes map[string]int `json:"outcomes"`
+		}{r.Results, r.Outcomes()})
+	case OutputMarkdown:
+		return r.writeMarkdown(w)
+	case OutputTable:
+		return r.writeTable(w)
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+// Outcomes counts results by outcome
+func (r *Report) Outcomes() map[string]int {
+	outcomes := make(map[string]int)
+	for _, result := range r.Results {
+		outcomes[result.Outcome]++
+	}
+	return outcomes
+}
+
+var resultColumns = []string{"OUTCOME", "HOST", "TYPE", "RESOURCE", "ZONE", "RECORD", "OWNER", "ERROR"}
+
+func (r *Result) columns() []string {
+	return []string{r.Outcome, r.Host, r.RecordType, r.Resource, r.Zone, r.Record, r.Owner, r.Error}
+}
+
+func (r *Report) writeTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(resultColumns, "\t"))
+	for _, result := range r.Results {
+		fmt.Fprintln(table, strings.Join(result.columns(), "\t"))
+	}
+	fmt.Fprintln(table, "\nOUTCOME\tCOUNT")
+	outcomes := r.Outcomes()
+	for _, outcome := range outcomeOrder {
+		if outcomes[outcome] > 0 {
+			fmt.Fprintf(table, "%s\t%d\n", outcome, outcomes[outcome])
+		}
+	}
+	return table.Flush()
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	fmt.Fprintln(w, "| Outcome | Host | Type | Resource | Zone | Record | Owner | Error |\n| --- | --- | --- | --- | --- | --- | --- | --- |")
+	for _, result := range r.Results {
+		cells := result.columns()
+		for i, cell := range cells {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+
+	fmt.Fprintln(w, "\n| Outcome | Count |\n| --- | --- |")
+	outcomes := r.Outcomes()
+	for _, outcome := range outcomeOrder {
+		if outcomes[outcome] > 0 {
+			fmt.Fprintf(w, "| %s | %d |\n", outcome, outcomes[outcome])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createResultZone() *registry.Zone {
+	zone := registry.NewZone("dummy.host")
+	for _, hostOwner := range [][]string{
+		{"webserver.dummy.host", "cluster-1"},
+		{"api.dummy.host", currentOwnerId},
+		{"admin.dummy.host", "cluster-0"},
+	} {
+		host := registry.NewHost(hostOwner[0], "A", "127.0.0.1")
+		host.AddRegistryRecord(&registry.Record{Name: "registry1-" + registry.Hostname(hostOwner[0]), Owner: hostOwner[1], Resource: testEndpointResource})
+		zone.AddHost(host)
+	}
+	zone.AddHost(registry.NewHost("mail.dummy.host", "A", "127.0.0.2"))
+	return zone
+}
+
+func TestSelector_ClaimEndpointsOwnership_Results(t *testing.T) {
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	selector := NewSelector(cfg, testProvider)
+	endpoints := []*registry.Endpoint{
+		{Host: "webserver.dummy.host", Resource: testEndpointResource},
+		{Host: "api.dummy.host", Resource: testEndpointResource},
+		{Host: "admin.dummy.host", Resource: testEndpointResource},
+		{Host: "mail.dummy.host", Resource: testEndpointResource},
+		{Host: "missing.dummy.host", Resource: testEndpointResource},
+		{Host: "api.notdummy.host", Resource: testEndpointResource},
+	}
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{createResultZone()})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+
+	var outcomes []string
+	for _, result := range selector.Report().Results {
+		outcomes = append(outcomes, result.Host+"="+result.Outcome)
+	}
+	assert.Equal(t, []string{
+		"webserver.dummy.host=updated",
+		"api.dummy.host=up-to-date",
+		"admin.dummy.host=owner-not-allowed",
+		"mail.dummy.host=no-registry",
+		"missing.dummy.host=no-host",
+		"api.notdummy.host=no-zone",
+	}, outcomes)
+	assert.Equal(t, "cluster-1", selector.Report().Results[0].Owner, "Owner before the change reported")
+	assert.True(t, selector.Report().HasProblems())
+}
+
+func TestSelector_ClaimEndpointsOwnership_RecordTypeResults(t *testing.T) {
+	selector := NewSelector(cfg, &mockProvider{})
+	zone := registry.NewZone("dummy.host")
+	zone.AddHost(registry.NewHost("mail.dummy.host", "A", "127.0.0.2"))
+	zone.AddHost(registry.NewHost("mail.dummy.host", "MX", "10 mx.dummy.host"))
+	endpoints := []*registry.Endpoint{{Host: "mail.dummy.host", Resource: testEndpointResource}}
+
+	_, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+	assert.NoError(t, err)
+
+	var recordTypes []string
+	for _, result := range selector.Report().Results {
+		recordTypes = append(recordTypes, result.RecordType+"="+result.Outcome)
+	}
+	assert.Equal(t, []string{"A=no-registry", "MX=no-registry"}, recordTypes, "Record sets of the host told apart")
+}
+
+func TestSelector_ClaimEndpointsOwnership_FailedResult(t *testing.T) {
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(0, errors.New("api error"))
+	selector := NewSelector(cfg, testProvider)
+	endpoints := []*registry.Endpoint{{Host: "webserver.dummy.host", Resource: testEndpointResource}}
+
+	_, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{createResultZone()})
+	assert.Error(t, err)
+	assert.Len(t, selector.Report().Results, 1)
+	assert.Equal(t, OutcomeFailed, selector.Report().Results[0].Outcome)
+	assert.Equal(t, "api error", selector.Report().Results[0].Error)
+}
+
+func TestReport_WriteResults(t *testing.T) {
+	report := NewReport()
+	zone := registry.NewZone("dummy.host")
+	record := &registry.Record{Name: "registry1-a-webserver.dummy.host", RecordType: "A", Owner: "cluster-1"}
+	report.AddResult(NewResult(&registry.Endpoint{Host: "webserver.dummy.host", Resource: testEndpointResource}, zone, record, OutcomeUpdated, nil))
+	report.AddResult(NewResult(&registry.Endpoint{Host: "api.notdummy.host", Resource: testEndpointResource}, nil, nil, OutcomeNoZone, nil))
+	assert.True(t, report.HasProblems())
+	assert.Equal(t, map[string]int{OutcomeUpdated: 1, OutcomeNoZone: 1}, report.Outcomes())
+
+	var tableOutput bytes.Buffer
+	assert.NoError(t, report.WriteResults(&tableOutput, OutputTable))
+	assert.Contains(t, tableOutput.String(), "updated  webserver.dummy.host  A     ingress/test/webserver  dummy.host  registry1-a-webserver.dummy.host  cluster-1")
+	assert.Contains(t, tableOutput.String(), "no-zone  1")
+
+	var markdownOutput bytes.Buffer
+	assert.NoError(t, report.WriteResults(&markdownOutput, OutputMarkdown))
+	assert.Contains(t, markdownOutput.String(), "| no-zone | api.notdummy.host |  | ingress/test/webserver |  |  |  |  |\n")
+	assert.Contains(t, markdownOutput.String(), "| updated | 1 |\n")
+
+	var jsonOutput bytes.Buffer
+	assert.NoError(t, report.WriteResults(&jsonOutput, OutputJSON))
+	assert.Contains(t, jsonOutput.String(), `"outcome": "no-zone"`)
+	assert.Contains(t, jsonOutput.String(), `"recordType": "A"`)
+
+	assert.Error(t, report.WriteResults(&tableOutput, OutputCSV))
+}
@@ -2,6 +2,9 @@ package pkg
 
 import (
 	"context"
+	"errors"
+	"sync"
+
 	"github.com/matic-insurance/dns-tager/provider"
 	"github.com/matic-insurance/dns-tager/registry"
 	log "github.com/sirupsen/logrus"
@@ -10,124 +13,431 @@ import (
 type Selector struct {
 	cfg      *Config
 	provider provider.Provider
+	report   *Report
+	journal  *Journal
+	// failures collected with continue on error policy
+	failures   []error
+	failuresMu sync.Mutex
+	// skipped records were rewritten since zones were read
+	skipped   []*ZoneRecord
+	skippedMu sync.Mutex
 }
 
 func NewSelector(cfg *Config, provider provider.Provider) *Selector {
-	return &Selector{cfg: cfg, provider: provider}
+	return &Selector{cfg: cfg, provider: provider, report: NewReport()}
+}
+
+// UseJournal records every registry record change in the journal, applied changes of resumed journal are skipped
+func (s *Selector) UseJournal(journal *Journal) {
+	s.journal = journal
+}
+
+// Report returns summary of the endpoints processed by the selector
+func (s *Selector) Report() *Report {
+	return s.report
+}
+
+// SkippedRecords returns registry records release, reassign, freeze, unfreeze and restore left unchanged
+// because they were rewritten since zones were read
+func (s *Selector) SkippedRecords() []*ZoneRecord {
+	s.skippedMu.Lock()
+	defer s.skippedMu.Unlock()
+	return append([]*ZoneRecord{}, s.skipped...)
 }
 
 func (s *Selector) ClaimEndpointsOwnership(ctx context.Context, endpoints []*registry.Endpoint, zones []*registry.Zone) (updatedRecords int, err error) {
-	for _, endpoint := range endpoints {
-		log.Debugf("Processing '%s'", endpoint)
-		zone := findEndpointZone(endpoint, zones)
-		if zone == nil {
-			log.Warnf("Can't find DNS zone information for '%s'", endpoint)
+	return s.processEndpoints(ctx, endpoints, zones, s.claimEndpoint)
+}
+
+func (s *Selector) ClaimEndpointsResource(ctx context.Context, endpoints []*registry.Endpoint, zones []*registry.Zone) (updatedRecords int, err error) {
+	return s.processEndpoints(ctx, endpoints, zones, s.claimEndpointResource)
+}
+
+// AdoptEndpoints creates registry records owned by current owner for unmanaged hosts of the endpoints
+func (s *Selector) AdoptEndpoints(ctx context.Context, endpoints []*registry.Endpoint, zones []*registry.Zone) (createdRecords int, err error) {
+	return s.processEndpoints(ctx, endpoints, zones, s.adoptEndpoint)
+}
+
+type endpointProcessor func(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (int, error)
+
+// processEndpoints runs processor for every endpoint with up to configured concurrency. Endpoints are unique per host
+// after conflicts resolution, so workers never change the same host. Processing stops on the first error, letting
+// started workers finish their endpoints, or, with continue on error policy, all failures are returned joined
+func (s *Selector) processEndpoints(ctx context.Context, endpoints []*registry.Endpoint, zones []*registry.Zone, process endpointProcessor) (changedRecords int, err error) {
+	s.failures = nil
+	for _, zone := range zones {
+		zone.IndexHosts()
+	}
+	var (
+		mu       sync.Mutex
+		abortErr error
+		wg       sync.WaitGroup
+	)
+	resolved := s.resolveConflicts(endpoints)
+	s.addConflictResults(zones)
+	workers := make(chan struct{}, s.concurrency())
+	for _, endpoint := range resolved {
+		workers <- struct{}{}
+		mu.Lock()
+		stop := abortErr != nil || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			<-workers
+			break
+		}
+		wg.Add(1)
+		go func(endpoint *registry.Endpoint) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			log.Debugf("Processing '%s'", endpoint)
+			zone := registry.FindZone(zones, endpoint.Host)
+			s.report.AddZoneEndpoint(zone, endpoint)
+			if zone == nil {
+				log.Debugf("Can't find DNS zone information for '%s'", endpoint)
+				s.addResult(endpoint, nil, nil, OutcomeNoZone, nil)
+				return
+			}
+			newChangedRecords, err := process(ctx, endpoint, zone)
+
+			mu.Lock()
+			defer mu.Unlock()
+			changedRecords += newChangedRecords
+			if err != nil && abortErr == nil {
+				abortErr = err
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	if abortErr != nil {
+		return changedRecords, abortErr
+	}
+	if err := ctx.Err(); err != nil {
+		return changedRecords, errors.Join(append(s.failures, err)...)
+	}
+	return changedRecords, errors.Join(s.failures...)
+}
+
+// concurrency returns number of endpoints processed in parallel, endpoints are processed one by one by default
+func (s *Selector) concurrency() int {
+	if s.cfg.Concurrency < 1 {
+		return 1
+	}
+	return s.cfg.Concurrency
+}
+
+func (s *Selector) claimEndpoint(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (updatedRecords int, err error) {
+	registryRecords, redundantRecords, found := s.selectEndpointRecords(endpoint, zone)
+	if !found {
+		return 0, nil
+	}
+	updatedRecords, err = s.deleteRedundantRecords(ctx, endpoint, zone, redundantRecords, func(owner string) bool {
+		return s.isAlreadyOwned(owner) || s.isAllowedOwner(owner)
+	})
+	if err != nil {
+		return updatedRecords, err
+	}
+	for _, registryRecord := range registryRecords {
+		if s.isAlreadyOwned(registryRecord.Owner) {
+			log.Debugf("Owner info up to date for '%s'", registryRecord)
+			s.addResult(endpoint, zone, registryRecord, OutcomeUpToDate, nil)
+			continue
+		}
+		if !s.isAllowedOwner(registryRecord.Owner) {
+			log.Warnf("Owner not updated. Unsupported previous owner. '%s'", registryRecord.Owner)
+			s.addResult(endpoint, zone, registryRecord, OutcomeOwnerNotAllowed, nil)
 			continue
 		}
-		newUpdatedRecords, err := s.claimEndpoint(ctx, endpoint, zone)
+
+		log.Infof("Updating owner info for '%s' to '%s'", registryRecord, s.cfg.CurrentOwnerID)
+
+		updatedRecord := registryRecord.NewRecord(s.cfg.CurrentOwnerID, endpoint.Resource)
+		updates, _, err := s.changeRecord(ctx, endpoint, zone, ActionUpdate, registryRecord, updatedRecord)
+		updatedRecords += updates
 		if err != nil {
 			return updatedRecords, err
 		}
-		updatedRecords += newUpdatedRecords
 	}
 	return updatedRecords, nil
 }
 
-func (s *Selector) ClaimEndpointsResource(ctx context.Context, endpoints []*registry.Endpoint, zones []*registry.Zone) (updatedRecords int, err error) {
-	for _, endpoint := range endpoints {
-		log.Debugf("Processing '%s'", endpoint)
-		zone := findEndpointZone(endpoint, zones)
-		if zone == nil {
-			log.Warnf("Can't find DNS zone information for '%s'", endpoint)
+func (s *Selector) claimEndpointResource(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (updatedRecords int, err error) {
+	registryRecords, redundantRecords, found := s.selectEndpointRecords(endpoint, zone)
+	if !found {
+		return 0, nil
+	}
+	updatedRecords, err = s.deleteRedundantRecords(ctx, endpoint, zone, redundantRecords, s.isAllowedResourceOwner)
+	if err != nil {
+		return updatedRecords, err
+	}
+	for _, registryRecord := range registryRecords {
+		if !s.isAllowedResourceOwner(registryRecord.Owner) {
+			log.Warnf("Resource not updated. Owner '%s' is not allowed by '%s' owner policy", registryRecord.Owner, s.cfg.ResourceOwnerPolicy)
+			s.addResult(endpoint, zone, registryRecord, OutcomeOwnerNotAllowed, nil)
+			continue
+		}
+
+		log.Debugf("Resource on DNSimple: '%s'", registryRecord.Resource)
+		log.Debugf("Resource on K8S: '%s'", endpoint.Resource)
+
+		if registryRecord.Resource == endpoint.Resource {
+			log.Debugf("Resource info up to date for '%s'", registryRecord)
+			s.addResult(endpoint, zone, registryRecord, OutcomeUpToDate, nil)
 			continue
 		}
-		newUpdatedRecords, err := s.claimEndpointResource(ctx, endpoint, zone)
+
+		log.Infof("Updating Resource info for '%s' to '%s'", registryRecord, endpoint.Resource)
+		updatedRecord := registryRecord.NewRecord(registryRecord.Owner, endpoint.Resource)
+		updates, _, err := s.changeRecord(ctx, endpoint, zone, ActionUpdate, registryRecord, updatedRecord)
+		updatedRecords += updates
 		if err != nil {
 			return updatedRecords, err
 		}
-		updatedRecords += newUpdatedRecords
 	}
 	return updatedRecords, nil
 }
 
-func (s *Selector) claimEndpoint(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (updatedRecords int, err error) {
-	hostDiscovered := false
-	for _, host := range zone.Hosts {
-		if endpoint.Host == host.Name {
-			log.Debugf("Host record found for '%s'", endpoint)
-			hostDiscovered = true
-			if host.IsManaged() {
-				for _, registryRecord := range host.RegistryRecords {
-					if s.isAlreadyOwned(registryRecord.Owner) {
-						log.Debugf("Owner info up to date for '%s'", registryRecord)
-						continue
-					}
-					if !s.isAllowedOwner(registryRecord.Owner) {
-						log.Warnf("Owner not updated. Unsupported previous owner. '%s'", registryRecord.Owner)
-						continue
-					}
-
-					log.Infof("Updating owner info for '%s' to '%s'", registryRecord, s.cfg.CurrentOwnerID)
-
-					updatedRecord := registryRecord.NewRecord(s.cfg.CurrentOwnerID, endpoint.Resource)
-					updates, err := s.provider.UpdateRegistryRecord(ctx, zone, updatedRecord)
-					updatedRecords += updates
-					if err != nil {
-						return updatedRecords, err
-					}
-				}
-			} else {
-				log.Warnf("Missing registry records for '%s'", endpoint)
-			}
+// selectEndpointRecords returns distinct registry records of all record sets of the endpoint host to update and
+// redundant records to delete. Hosts without registry records are reported, found is false when the zone has no host
+func (s *Selector) selectEndpointRecords(endpoint *registry.Endpoint, zone *registry.Zone) (registryRecords []*registry.Record, redundantRecords []*registry.Record, found bool) {
+	hosts := zone.FindHosts(endpoint.Host)
+	if len(hosts) == 0 {
+		log.Warnf("Missing host record for '%s'", endpoint)
+		s.addResult(endpoint, zone, nil, OutcomeNoHost, nil)
+		return nil, nil, false
+	}
+	for _, host := range hosts {
+		log.Debugf("Host record found for '%s'", endpoint)
+		if !host.IsManaged() {
+			log.Warnf("Missing registry records for '%s'", endpoint)
+			s.addHostResult(endpoint, zone, host, nil, OutcomeNoRegistry)
+			continue
 		}
+		selected, redundant := s.selectRegistryRecords(host)
+		registryRecords = append(registryRecords, selected...)
+		redundantRecords = append(redundantRecords, redundant...)
 	}
-	if !hostDiscovered {
+	return registry.UniqueRecords(registryRecords), registry.UniqueRecords(redundantRecords), true
+}
+
+func (s *Selector) adoptEndpoint(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (createdRecords int, err error) {
+	hosts := zone.FindHosts(endpoint.Host)
+	if len(hosts) == 0 {
 		log.Warnf("Missing host record for '%s'", endpoint)
+		s.addResult(endpoint, zone, nil, OutcomeNoHost, nil)
+		return 0, nil
 	}
-	return updatedRecords, nil
+	unmanagedHosts := make([]*registry.Host, 0, len(hosts))
+	for _, host := range hosts {
+		log.Debugf("Host record found for '%s'", endpoint)
+		if host.IsManaged() {
+			log.Debugf("Host already has registry records '%s'", host)
+			s.addHostResult(endpoint, zone, host, host.RegistryRecords[0], OutcomeUpToDate)
+			continue
+		}
+		unmanagedHosts = append(unmanagedHosts, host)
+	}
+	if len(unmanagedHosts) == 0 {
+		return 0, nil
+	}
+
+	for _, record := range s.adoptionRecords(zone, unmanagedHosts, endpoint) {
+		log.Infof("Creating registry record '%s'", record)
+		creates, outcome, err := s.changeRecord(ctx, endpoint, zone, ActionCreate, nil, record)
+		createdRecords += creates
+		if err != nil {
+			return createdRecords, err
+		}
+		if outcome != OutcomeCreated {
+			continue
+		}
+		for _, host := range unmanagedHosts {
+			if record.RecordType == "" || record.RecordType == host.RecordType {
+				host.AddRegistryRecord(record)
+			}
+		}
+	}
+	return createdRecords, nil
 }
 
-func (s *Selector) claimEndpointResource(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone) (updatedRecords int, err error) {
-	hostDiscovered := false
-	for _, host := range zone.Hosts {
-		if endpoint.Host == host.Name {
-			log.Debugf("Host record found for '%s'", endpoint)
-			hostDiscovered = true
-			if host.IsManaged() {
-				for _, registryRecord := range host.RegistryRecords {
-
-					log.Debugf("Resource on DNSimple: '%s'", registryRecord.Resource)
-					log.Debugf("Resource on K8S: '%s'", endpoint.Resource)
-
-					if registryRecord.Resource == endpoint.Resource {
-						log.Debugf("Resource info up to date for '%s'", registryRecord)
-						continue
-					}
-
-					log.Infof("Updating Resource info for '%s' to '%s'", registryRecord, endpoint.Resource)
-					updatedRecord := registryRecord.NewRecord(registryRecord.Owner, endpoint.Resource)
-					updates, err := s.provider.UpdateRegistryRecord(ctx, zone, updatedRecord)
-
-					updatedRecords += updates
-					if err != nil {
-						return updatedRecords, err
-					}
-				}
+// adoptionRecords returns registry records External DNS expects for record sets of the host: DynamoDB items keyed
+// by host name and type, or legacy record shared by the record sets and new format records named with configured
+// prefix and suffix
+func (s *Selector) adoptionRecords(zone *registry.Zone, hosts []*registry.Host, endpoint *registry.Endpoint) []*registry.Record {
+	records := make([]*registry.Record, 0, len(hosts)+1)
+	if s.cfg.Registry == "dynamodb" {
+		for _, host := range hosts {
+			records = append(records, &registry.Record{Name: host.Name, RecordType: host.RecordType, Owner: s.cfg.CurrentOwnerID, Resource: endpoint.Resource})
+		}
+		return records
+	}
+
+	matcher := s.cfg.Matcher()
+	if name, ok := zoneName(zone, matcher.LegacyNames(zone, hosts[0])); ok {
+		records = append(records, &registry.Record{Name: name, Owner: s.cfg.CurrentOwnerID, Resource: endpoint.Resource, Encrypted: s.cfg.TXTEncryptEnabled})
+	}
+	for _, host := range hosts {
+		if name, ok := zoneName(zone, matcher.RecordTypeNames(zone, host)); ok {
+			records = append(records, &registry.Record{Name: name, RecordType: host.RecordType, Owner: s.cfg.CurrentOwnerID, Resource: endpoint.Resource, Encrypted: s.cfg.TXTEncryptEnabled})
+		}
+	}
+	return records
+}
+
+// zoneName returns the first registry record name inside the zone. Apex hosts have registry names affixed to the
+// zone name (<prefix>example.com) first, those are outside the zone and can't be created in it
+func zoneName(zone *registry.Zone, names []registry.Hostname) (registry.Hostname, bool) {
+	for _, name := range names {
+		if zone.IsManagingName(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// selectRegistryRecords splits registry records of the host into records to update and redundant records
+// to delete according to duplicate records strategy. Conflicting records are added to the report
+func (s *Selector) selectRegistryRecords(host *registry.Host) (registryRecords []*registry.Record, redundantRecords []*registry.Record) {
+	conflict := registry.AnalyzeRegistryRecords(host)
+	if conflict == nil {
+		return host.RegistryRecords, nil
+	}
+	if conflict.HasConflict() && s.report != nil {
+		s.report.AddRecordConflict(conflict)
+	}
+
+	switch s.cfg.DuplicateRecords {
+	case DuplicateRecordsCanonical:
+		return []*registry.Record{conflict.Canonical}, nil
+	case DuplicateRecordsDelete:
+		for _, record := range host.RegistryRecords {
+			if conflict.IsDuplicate(record) {
+				redundantRecords = append(redundantRecords, record)
 			} else {
-				log.Warnf("Missing registry records for '%s'", endpoint)
+				registryRecords = append(registryRecords, record)
 			}
 		}
+		return registryRecords, redundantRecords
+	default:
+		return host.RegistryRecords, nil
 	}
-	if !hostDiscovered {
-		log.Warnf("Missing host record for '%s'", endpoint)
+}
+
+func (s *Selector) deleteRedundantRecords(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone, records []*registry.Record, isAllowed func(owner string) bool) (deletedRecords int, err error) {
+	for _, record := range records {
+		if !isAllowed(record.Owner) {
+			log.Warnf("Duplicate registry record not deleted. Owner '%s' is not allowed. '%s'", record.Owner, record)
+			s.addResult(endpoint, zone, record, OutcomeOwnerNotAllowed, nil)
+			continue
+		}
+
+		log.Infof("Deleting duplicate registry record '%s'", record)
+		deletes, _, err := s.changeRecord(ctx, endpoint, zone, ActionDelete, record, nil)
+		deletedRecords += deletes
+		if err != nil {
+			return deletedRecords, err
+		}
+	}
+	return deletedRecords, nil
+}
+
+// changeRecord applies registry record change through the journal and records its outcome in the report.
+// Record is nil for created records and changed is nil for deleted records. Error is returned only when
+// processing should be aborted
+func (s *Selector) changeRecord(ctx context.Context, endpoint *registry.Endpoint, zone *registry.Zone, action string, record *registry.Record, changed *registry.Record) (changes int, outcome string, err error) {
+	reported := record
+	if reported == nil {
+		reported = changed
+	}
+
+	var entry *JournalEntry
+	if s.journal != nil {
+		entry, err = s.journal.Plan(ctx, zone, action, record, changed)
+		switch {
+		case errors.Is(err, errRecordChanged):
+			log.Warnf("Registry record not changed. It differs from the record journal change was planned for. '%s'", reported)
+			s.addResult(endpoint, zone, reported, OutcomeChanged, nil)
+			return 0, OutcomeChanged, nil
+		case err != nil:
+			return 0, OutcomeFailed, s.fail(endpoint, zone, reported, err)
+		}
+	}
+
+	switch action {
+	case ActionCreate:
+		changes, err = s.provider.CreateRegistryRecord(ctx, zone, changed)
+		outcome = OutcomeCreated
+	case ActionDelete:
+		changes, err = s.provider.DeleteRegistryRecord(ctx, zone, record)
+		outcome = OutcomeDeleted
+	default:
+		changes, err = provider.UpdateRegistryRecordIf(ctx, s.provider, zone, record, changed)
+		outcome = OutcomeUpdated
+	}
+	if entry != nil {
+		if journalErr := s.journal.Finish(ctx, entry, err); journalErr != nil && err == nil {
+			err = journalErr
+		}
+	}
+	if errors.Is(err, provider.ErrRecordChanged) {
+		log.Warnf("Registry record not changed. It was rewritten since zones were read. '%s'", reported)
+		s.addResult(endpoint, zone, reported, OutcomeChanged, err)
+		return 0, OutcomeChanged, nil
+	}
+	if err != nil {
+		return changes, OutcomeFailed, s.fail(endpoint, zone, reported, err)
+	}
+	s.addResult(endpoint, zone, reported, outcome, nil)
+	return changes, outcome, nil
+}
+
+// updateRecord updates registry record only if it still has owner and resource it was read with,
+// records rewritten since zones were read are skipped with a warning and listed in SkippedRecords
+func (s *Selector) updateRecord(ctx context.Context, zone *registry.Zone, record *registry.Record, updated *registry.Record) (int, error) {
+	updates, err := provider.UpdateRegistryRecordIf(ctx, s.provider, zone, record, updated)
+	if errors.Is(err, provider.ErrRecordChanged) {
+		log.Warnf("Registry record not updated. It was rewritten since zones were read. '%s'", record)
+		s.skippedMu.Lock()
+		s.skipped = append(s.skipped, &ZoneRecord{Zone: zone, Record: record})
+		s.skippedMu.Unlock()
+		return 0, nil
+	}
+	return updates, err
+}
+
+// addResult records decision in the report, selectors created without report skip it
+func (s *Selector) addResult(endpoint *registry.Endpoint, zone *registry.Zone, record *registry.Record, outcome string, err error) {
+	if s.report != nil {
+		s.report.AddResult(NewResult(endpoint, zone, record, outcome, err))
+	}
+}
+
+// addHostResult records decision made for the host record set, so record sets of the same host are told apart
+func (s *Selector) addHostResult(endpoint *registry.Endpoint, zone *registry.Zone, host *registry.Host, record *registry.Record, outcome string) {
+	if s.report != nil {
+		result := NewResult(endpoint, zone, record, outcome, nil)
+		result.RecordType = host.RecordType
+		s.report.AddResult(result)
 	}
-	return updatedRecords, nil
 }
 
 func (s *Selector) isAlreadyOwned(owner string) bool {
 	return owner == s.cfg.CurrentOwnerID
 }
 
+// isAllowedResourceOwner checks record owner against resource mode owner policy, current owner only by default
+func (s *Selector) isAllowedResourceOwner(owner string) bool {
+	switch s.cfg.ResourceOwnerPolicy {
+	case ResourceOwnerAny:
+		return true
+	case ResourceOwnerAllowed:
+		return s.isAlreadyOwned(owner) || s.isAllowedOwner(owner)
+	default:
+		return s.isAlreadyOwned(owner)
+	}
+}
+
 func (s *Selector) isAllowedOwner(owner string) bool {
 	for _, previousOwner := range s.cfg.PreviousOwnerIDs {
 		if previousOwner == owner {
@@ -136,12 +446,3 @@ func (s *Selector) isAllowedOwner(owner string) bool {
 	}
 	return false
 }
-
-func findEndpointZone(endpoint *registry.Endpoint, zones []*registry.Zone) *registry.Zone {
-	for _, zone := range zones {
-		if zone.IsManagingEndpoint(endpoint) {
-			return zone
-		}
-	}
-	return nil
-}
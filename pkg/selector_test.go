@@ -3,18 +3,21 @@ package pkg
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/matic-insurance/dns-tager/provider"
 	"github.com/matic-insurance/dns-tager/registry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
 )
 
+const testEndpointHost = "webserver.dummy.host"
+
 var (
 	testProvider          *mockProvider
 	currentOwnerId        = "cluster-2"
 	testEndpointResource  = "ingress/test/webserver"
 	testEndpointResource2 = "ingress/test/webserver2"
-	testEndpointHost      = "webserver.dummy.host"
 	cfg                   = &Config{
 		CurrentOwnerID:   currentOwnerId,
 		PreviousOwnerIDs: []string{"cluster-1"},
@@ -25,7 +28,7 @@ type mockProvider struct {
 	mock.Mock
 }
 
-func (p *mockProvider) ReadZones(_ context.Context) ([]*registry.Zone, error) {
+func (p *mockProvider) ReadZones(_ context.Context, _ *registry.Matcher) ([]*registry.Zone, error) {
 	panic("implement me")
 }
 
@@ -34,10 +37,30 @@ func (p *mockProvider) UpdateRegistryRecord(ctx context.Context, zone *registry.
 	return args.Int(0), args.Error(1)
 }
 
+func (p *mockProvider) CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (createdRecords int, err error) {
+	args := p.Called(ctx, zone, record)
+	return args.Int(0), args.Error(1)
+}
+
+func (p *mockProvider) DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (deletedRecords int, err error) {
+	args := p.Called(ctx, zone, record)
+	return args.Int(0), args.Error(1)
+}
+
 func (p *mockProvider) Whoami(_ context.Context) string {
 	panic("implement me")
 }
 
+// conditionalMockProvider supports conditional registry record updates
+type conditionalMockProvider struct {
+	mockProvider
+}
+
+func (p *conditionalMockProvider) UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (updatedRecords int, err error) {
+	args := p.Called(ctx, zone, expected, record)
+	return args.Int(0), args.Error(1)
+}
+
 func TestSelector_UpdateRegistryRecords_NoEndpointHost(t *testing.T) {
 	testProvider = &mockProvider{}
 	selector := Selector{provider: testProvider, cfg: cfg}
@@ -88,6 +111,24 @@ func TestSelector_UpdateRegistryRecords_ProviderError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSelector_UpdateRegistryRecords_RecordChanged(t *testing.T) {
+	testProvider := &conditionalMockProvider{}
+	selector := NewSelector(cfg, testProvider)
+	endpoint := &registry.Endpoint{Host: testEndpointHost, Resource: testEndpointResource}
+	zone := createTestZone(cfg.PreviousOwnerIDs[0], testEndpointResource)
+
+	testProvider.On("UpdateRegistryRecordIf", context.Background(), zone, mock.MatchedBy(func(expected *registry.Record) bool {
+		return expected.Owner == cfg.PreviousOwnerIDs[0]
+	}), mock.Anything).Return(1, nil).Once()
+	testProvider.On("UpdateRegistryRecordIf", context.Background(), zone, mock.Anything, mock.Anything).Return(0, fmt.Errorf("%w: test", provider.ErrRecordChanged))
+
+	updates, err := selector.claimEndpoint(context.Background(), endpoint, zone)
+	assert.NoError(t, err, "Rewritten record is skipped")
+	assert.Equal(t, 1, updates, "Made updates count returned")
+	assert.Equal(t, []string{OutcomeUpdated, OutcomeChanged}, []string{selector.Report().Results[0].Outcome, selector.Report().Results[1].Outcome})
+	testProvider.AssertNotCalled(t, "UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSelector_UpdateRegistryRecords_MultipleRegistries(t *testing.T) {
 	testProvider := &mockProvider{}
 	selector := Selector{provider: testProvider, cfg: cfg}
@@ -107,6 +148,19 @@ func TestSelector_UpdateRegistryRecords_MultipleRegistries(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSelector_UpdateRegistryRecords_CanonicalHostname(t *testing.T) {
+	testProvider := &mockProvider{}
+	selector := Selector{provider: testProvider, cfg: cfg}
+	endpoint := registry.NewEndpoint("WebServer.Dummy.Host.", testEndpointResource, "ingress")
+	zone := createTestZone(cfg.PreviousOwnerIDs[0], testEndpointResource)
+
+	testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil)
+
+	updates, err := selector.claimEndpoint(context.Background(), endpoint, zone)
+	assert.Equal(t, 2, updates, "Host matched regardless of case and trailing dot")
+	assert.NoError(t, err)
+}
+
 func TestSelector_UpdateRegistryRecords_SameResource(t *testing.T) {
 	testProvider = &mockProvider{}
 	selector := Selector{provider: testProvider, cfg: cfg}
@@ -119,6 +173,40 @@ func TestSelector_UpdateRegistryRecords_SameResource(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSelector_UpdateRegistryRecords_ResourceOwnerPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		owner   string
+		updates int
+	}{
+		{name: "Default policy current owner", policy: "", owner: currentOwnerId, updates: 2},
+		{name: "Default policy another owner", policy: "", owner: "cluster-0", updates: 0},
+		{name: "Current policy previous owner", policy: ResourceOwnerCurrent, owner: "cluster-1", updates: 0},
+		{name: "Allowed policy previous owner", policy: ResourceOwnerAllowed, owner: "cluster-1", updates: 2},
+		{name: "Allowed policy another owner", policy: ResourceOwnerAllowed, owner: "cluster-0", updates: 0},
+		{name: "Any policy another owner", policy: ResourceOwnerAny, owner: "cluster-0", updates: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testProvider := &mockProvider{}
+			policyCfg := &Config{CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: cfg.PreviousOwnerIDs, ResourceOwnerPolicy: tt.policy}
+			selector := Selector{provider: testProvider, cfg: policyCfg}
+			endpoint := &registry.Endpoint{Host: testEndpointHost, Resource: testEndpointResource2}
+			zone := createTestZone(tt.owner, testEndpointResource)
+
+			testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.MatchedBy(func(record *registry.Record) bool {
+				return record.Owner == tt.owner && record.Resource == testEndpointResource2
+			})).Return(1, nil)
+
+			updates, err := selector.claimEndpointResource(context.Background(), endpoint, zone)
+			testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", tt.updates)
+			assert.Equal(t, tt.updates, updates, "Correct updates count returned")
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestSelector_UpdateRegistryRecords_ProviderErrorResource(t *testing.T) {
 	testProvider = &mockProvider{}
 	selector := Selector{provider: testProvider, cfg: cfg}
@@ -133,11 +221,197 @@ func TestSelector_UpdateRegistryRecords_ProviderErrorResource(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSelector_UpdateRegistryRecords_SharedBetweenRecordSets(t *testing.T) {
+	testProvider := &mockProvider{}
+	selector := Selector{provider: testProvider, cfg: cfg}
+	endpoint := &registry.Endpoint{Host: testEndpointHost, Resource: testEndpointResource}
+	zone := registry.NewZone("dummy.host")
+	record := &registry.Record{Name: "registry1-" + testEndpointHost, Owner: cfg.PreviousOwnerIDs[0], Resource: testEndpointResource}
+	for _, recordType := range []string{"A", "AAAA"} {
+		host := registry.NewHost(testEndpointHost, recordType, "127.0.0.1", "127.0.0.2")
+		host.AddRegistryRecord(record)
+		zone.AddHost(host)
+	}
+
+	testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil)
+
+	updates, err := selector.claimEndpoint(context.Background(), endpoint, zone)
+	testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", 1)
+	assert.Equal(t, 1, updates, "Distinct registry records counted")
+	assert.NoError(t, err)
+
+	record.Owner = currentOwnerId
+	updates, err = selector.claimEndpointResource(context.Background(), &registry.Endpoint{Host: testEndpointHost, Resource: testEndpointResource2}, zone)
+	testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", 2)
+	assert.Equal(t, 1, updates, "Distinct registry records counted")
+	assert.NoError(t, err)
+}
+
+func TestSelector_UpdateRegistryRecords_DuplicateRecords(t *testing.T) {
+	tests := []struct {
+		strategy string
+		updates  int
+		deletes  int
+	}{
+		{strategy: DuplicateRecordsAll, updates: 3},
+		{strategy: DuplicateRecordsCanonical, updates: 1},
+		{strategy: DuplicateRecordsDelete, updates: 2, deletes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			testProvider := &mockProvider{}
+			strategyCfg := &Config{CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: cfg.PreviousOwnerIDs, DuplicateRecords: tt.strategy}
+			selector := NewSelector(strategyCfg, testProvider)
+			endpoint := &registry.Endpoint{Host: testEndpointHost, Resource: testEndpointResource}
+			zone := registry.NewZone("dummy.host")
+			host := registry.NewHost(testEndpointHost, "A", "127.0.0.1")
+			host.AddRegistryRecord(&registry.Record{Name: "registry1-" + testEndpointHost, Owner: "cluster-1", Resource: testEndpointResource})
+			host.AddRegistryRecord(&registry.Record{Name: "registry1-a-" + testEndpointHost, RecordType: "A", Owner: "cluster-1", Resource: testEndpointResource})
+			host.AddRegistryRecord(&registry.Record{Name: "registry1-a-" + testEndpointHost, RecordType: "A", Owner: "cluster-1", Resource: testEndpointResource2})
+			zone.AddHost(host)
+
+			testProvider.On("UpdateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil)
+			testProvider.On("DeleteRegistryRecord", context.Background(), zone, host.RegistryRecords[2]).Return(1, nil)
+
+			updates, err := selector.claimEndpoint(context.Background(), endpoint, zone)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.updates+tt.deletes, updates, "Updated and deleted records counted")
+			testProvider.AssertNumberOfCalls(t, "UpdateRegistryRecord", tt.updates)
+			testProvider.AssertNumberOfCalls(t, "DeleteRegistryRecord", tt.deletes)
+			assert.Len(t, selector.Report().RecordConflicts, 1, "Conflicting records reported")
+		})
+	}
+}
+
+func TestSelector_ClaimEndpointsOwnership_ZoneReport(t *testing.T) {
+	testProvider := &mockProvider{}
+	selector := NewSelector(cfg, testProvider)
+	parent := createTestZone(currentOwnerId, testEndpointResource)
+	subzone := registry.NewZone("eu.dummy.host")
+	endpoints := []*registry.Endpoint{
+		{Host: testEndpointHost, Resource: testEndpointResource},
+		{Host: "api.eu.dummy.host", Resource: testEndpointResource},
+		{Host: "api.notdummy.host", Resource: testEndpointResource},
+	}
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{parent, subzone})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updates, "Zero updates count returned")
+	assert.Equal(t, map[string]int{"dummy.host": 1, "eu.dummy.host": 1}, selector.Report().ZoneEndpoints)
+	assert.Equal(t, []*registry.Endpoint{endpoints[2]}, selector.Report().UnmatchedEndpoints)
+}
+
 func createTestZone(owner string, resource string) *registry.Zone {
+	return buildTestZone(
+		testRecord{host: testEndpointHost, name: "registry1-" + testEndpointHost, owner: owner, resource: resource},
+		testRecord{host: testEndpointHost, name: "registry1-cname-" + testEndpointHost, owner: owner, resource: resource},
+	)
+}
+
+// testRecord is a registry record of the test zone attached to the record set of the host and type.
+// Records without host are orphans
+type testRecord struct {
+	host       string
+	recordType string
+	name       string
+	owner      string
+	resource   string
+}
+
+// buildTestZone creates dummy.host zone with registry records and record sets they are attached to.
+// Records with the same name are shared between record sets like legacy registry records
+func buildTestZone(records ...testRecord) *registry.Zone {
 	zone := registry.NewZone("dummy.host")
-	host := registry.NewHost(testEndpointHost, "", "")
-	host.AddRegistryRecord(&registry.Record{Name: "registry1-" + testEndpointHost, Owner: owner, Resource: resource})
-	host.AddRegistryRecord(&registry.Record{Name: "registry1-cname-" + testEndpointHost, Owner: owner, Resource: resource})
-	zone.AddHost(host)
+	hosts := make(map[string]*registry.Host)
+	registryRecords := make(map[string]*registry.Record)
+	for _, spec := range records {
+		record, ok := registryRecords[spec.name]
+		if !ok {
+			record = &registry.Record{Name: registry.Hostname(spec.name), Owner: spec.owner, Resource: spec.resource}
+			registryRecords[spec.name] = record
+			zone.AddRegistryRecord(record)
+		}
+		if spec.host == "" {
+			continue
+		}
+		host, ok := hosts[spec.host+"#"+spec.recordType]
+		if !ok {
+			host = registry.NewHost(spec.host, spec.recordType, "127.0.0.1")
+			hosts[spec.host+"#"+spec.recordType] = host
+			zone.AddHost(host)
+		}
+		host.AddRegistryRecord(record)
+	}
 	return zone
 }
+
+func TestSelector_AdoptEndpoints(t *testing.T) {
+	testProvider := &mockProvider{}
+	adoptCfg := &Config{CurrentOwnerID: currentOwnerId, TXTPrefix: "registry1-"}
+	selector := NewSelector(adoptCfg, testProvider)
+	zone := createTestZone("cluster-1", testEndpointResource)
+	for _, recordType := range []string{"A", "AAAA"} {
+		zone.AddHost(registry.NewHost("api.dummy.host", recordType, "127.0.0.1"))
+	}
+	endpoints := []*registry.Endpoint{
+		{Host: testEndpointHost, Resource: testEndpointResource},
+		{Host: "api.dummy.host", Resource: testEndpointResource2},
+	}
+
+	testProvider.On("CreateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil)
+
+	created, err := selector.AdoptEndpoints(context.Background(), endpoints, []*registry.Zone{zone})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created, "Shared legacy record created once")
+
+	var names []registry.Hostname
+	for _, call := range testProvider.Calls {
+		record := call.Arguments.Get(2).(*registry.Record)
+		assert.Equal(t, currentOwnerId, record.Owner)
+		assert.Equal(t, testEndpointResource2, record.Resource)
+		names = append(names, record.Name)
+	}
+	assert.Equal(t, []registry.Hostname{"registry1-api.dummy.host", "registry1-a-api.dummy.host", "registry1-aaaa-api.dummy.host"}, names)
+	assert.True(t, zone.Hosts[2].IsManaged(), "Created records attached to the host")
+}
+
+func TestSelector_AdoptEndpoints_Apex(t *testing.T) {
+	testProvider := &mockProvider{}
+	selector := NewSelector(&Config{CurrentOwnerID: currentOwnerId, TXTPrefix: "edns-"}, testProvider)
+	zone := registry.NewZone("dummy.host")
+	zone.AddHost(registry.NewHost("dummy.host", "A", "127.0.0.1"))
+	testProvider.On("CreateRegistryRecord", context.Background(), zone, mock.Anything).Return(1, nil)
+
+	created, err := selector.AdoptEndpoints(context.Background(), []*registry.Endpoint{{Host: "dummy.host", Resource: testEndpointResource}}, []*registry.Zone{zone})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created)
+
+	var names []registry.Hostname
+	for _, call := range testProvider.Calls {
+		names = append(names, call.Arguments.Get(2).(*registry.Record).Name)
+	}
+	assert.Equal(t, []registry.Hostname{"edns-.dummy.host", "edns-a-.dummy.host"}, names, "Apex registry records created inside the zone")
+	assert.Len(t, zone.Hosts[0].RegistryRecords, 2)
+}
+
+func TestSelector_ClaimEndpointsOwnership_Concurrency(t *testing.T) {
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	selector := NewSelector(&Config{CurrentOwnerID: currentOwnerId, PreviousOwnerIDs: []string{"cluster-1"}, Concurrency: 4}, testProvider)
+	zone := registry.NewZone("dummy.host")
+	var endpoints []*registry.Endpoint
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("host%d.dummy.host", i)
+		host := registry.NewHost(name, "A", "127.0.0.1")
+		host.AddRegistryRecord(&registry.Record{Name: registry.Hostname("registry1-" + name), Owner: "cluster-1", Resource: testEndpointResource})
+		zone.AddHost(host)
+		endpoints = append(endpoints, &registry.Endpoint{Host: registry.Hostname(name), Resource: testEndpointResource})
+	}
+
+	updates, err := selector.ClaimEndpointsOwnership(context.Background(), endpoints, []*registry.Zone{zone})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 20, updates)
+	assert.Len(t, selector.Report().Results, 20)
+	assert.Equal(t, map[string]int{"dummy.host": 20}, selector.Report().ZoneEndpoints)
+}
@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotVersion is the format version of the snapshot written by this build
+const SnapshotVersion = 1
+
+// SnapshotRecord is registry record as it was stored at the moment of snapshot
+type SnapshotRecord struct {
+	Zone string `json:"zone"`
+	// ID of the record in the DNS provider or the registry backend
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name"`
+	RecordType string            `json:"recordType,omitempty"`
+	Owner      string            `json:"owner"`
+	Resource   string            `json:"resource"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Encrypted  bool              `json:"encrypted,omitempty"`
+	Compressed bool              `json:"compressed,omitempty"`
+
+	// zone and record read from DNS, nil for records loaded from file
+	zone   *registry.Zone
+	record *registry.Record
+}
+
+// Record returns registry record with the snapshot contents
+func (r *SnapshotRecord) Record() *registry.Record {
+	record := &registry.Record{Name: registry.Hostname(r.Name), Owner: r.Owner, Resource: r.Resource, ID: r.ID, RecordType: r.RecordType, Encrypted: r.Encrypted, Compressed: r.Compressed}
+	for key, value := range r.Labels {
+		record.SetLabel(key, value)
+	}
+	return record
+}
+
+// sameContents compares what External DNS reads from the record, ids are ignored
+func (r *SnapshotRecord) sameContents(other *SnapshotRecord) bool {
+	return r.Owner == other.Owner && r.Resource == other.Resource && r.Encrypted == other.Encrypted &&
+		(len(r.Labels) == 0 && len(other.Labels) == 0 || reflect.DeepEqual(r.Labels, other.Labels))
+}
+
+func (r *SnapshotRecord) nameKey() string {
+	return r.Zone + "#" + r.Name + "#" + r.RecordType
+}
+
+// Snapshot lists registry records of the zones
+type Snapshot struct {
+	Version   int               `json:"version"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Registry  string            `json:"registry"`
+	Zones     []string          `json:"zones"`
+	Records   []*SnapshotRecord `json:"records"`
+}
+
+// NewSnapshot collects all registry records of the zones, including records without hosts but not lock leases. Record type of TXT
+// registry records is taken from the record name, so records without hosts have it as well
+func NewSnapshot(zones []*registry.Zone, cfg *Config, createdAt time.Time) *Snapshot {
+	snapshot := &Snapshot{Version: SnapshotVersion, CreatedAt: createdAt.UTC(), Registry: cfg.Registry, Zones: make([]string, 0), Records: make([]*SnapshotRecord, 0)}
+	matcher := cfg.Matcher()
+	for _, zone := range zones {
+		snapshot.Zones = append(snapshot.Zones, string(zone.Name))
+		for _, record := range zone.RegistryRecords {
+			if IsLease(record) {
+				continue
+			}
+			recordType := record.RecordType
+			if recordType == "" && cfg.Registry != "dynamodb" {
+				recordType = matcher.RecordType(zone, record.Name)
+			}
+			snapshot.Records = append(snapshot.Records, &SnapshotRecord{
+				Zone: string(zone.Name), ID: record.ID, Name: string(record.Name), RecordType: recordType,
+				Owner: record.Owner, Resource: record.Resource, Labels: record.Labels,
+				Encrypted: record.Encrypted, Compressed: record.Compressed,
+				zone: zone, record: record,
+			})
+		}
+	}
+	sort.SliceStable(snapshot.Records, func(i, j int) bool {
+		if snapshot.Records[i].nameKey() != snapshot.Records[j].nameKey() {
+			return snapshot.Records[i].nameKey() < snapshot.Records[j].nameKey()
+		}
+		return snapshot.Records[i].ID < snapshot.Records[j].ID
+	})
+	return snapshot
+}
+
+// ReadSnapshot loads snapshot written by WriteFile
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot '%s': %w", path, err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot '%s' has unsupported version %d", path, snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// WriteFile saves snapshot as indented JSON readable by the current user only, records of encrypted registry
+// are stored decrypted
+func (s *Snapshot) WriteFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	// permissions of existing file are not changed by WriteFile
+	return os.Chmod(path, 0o600)
+}
+
+// SnapshotChange is registry record with different contents in two snapshots
+type SnapshotChange struct {
+	Before *SnapshotRecord `json:"before"`
+	After  *SnapshotRecord `json:"after"`
+}
+
+// SnapshotDiff lists differences of registry records between base and target snapshots
+type SnapshotDiff struct {
+	// Added records exist only in target
+	Added []*SnapshotRecord `json:"added"`
+	// Removed records exist only in base
+	Removed []*SnapshotRecord `json:"removed"`
+	Changed []*SnapshotChange `json:"changed"`
+}
+
+// DiffSnapshots matches records by provider id first and by zone, name and record type second,
+// so records recreated with new ids are compared by contents
+func DiffSnapshots(base *Snapshot, target *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{Added: make([]*SnapshotRecord, 0), Removed: make([]*SnapshotRecord, 0), Changed: make([]*SnapshotChange, 0)}
+	matched := make(map[*SnapshotRecord]bool)
+	byID := make(map[string]*SnapshotRecord)
+	byName := make(map[string][]*SnapshotRecord)
+	for _, record := range target.Records {
+		if record.ID != "" {
+			byID[record.Zone+"#"+record.ID] = record
+		}
+		byName[record.nameKey()] = append(byName[record.nameKey()], record)
+	}
+
+	var unmatched []*SnapshotRecord
+	for _, record := range base.Records {
+		if other, ok := byID[record.Zone+"#"+record.ID]; ok && record.ID != "" && !matched[other] {
+			matched[other] = true
+			diff.addPair(record, other)
+			continue
+		}
+		unmatched = append(unmatched, record)
+	}
+	for _, record := range unmatched {
+		var found *SnapshotRecord
+		for _, other := range byName[record.nameKey()] {
+			if !matched[other] {
+				found = other
+				break
+			}
+		}
+		if found == nil {
+			diff.Removed = append(diff.Removed, record)
+			continue
+		}
+		matched[found] = true
+		diff.addPair(record, found)
+	}
+	for _, record := range target.Records {
+		if !matched[record] {
+			diff.Added = append(diff.Added, record)
+		}
+	}
+	return diff
+}
+
+func (d *SnapshotDiff) addPair(before *SnapshotRecord, after *SnapshotRecord) {
+	if !before.sameContents(after) {
+		d.Changed = append(d.Changed, &SnapshotChange{Before: before, After: after})
+	}
+}
+
+// IsEmpty reports whether snapshots have the same registry records
+func (d *SnapshotDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Write renders diff in table or json format
+func (d *SnapshotDiff) Write(w io.Writer, format string) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	case OutputTable:
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "CHANGE\tRECORD\tTYPE\tID\tOWNER\tRESOURCE")
+		for _, record := range d.Removed {
+			fmt.Fprintf(table, "removed\t%s\t%s\t%s\t%s\t%s\n", record.Name, record.RecordType, record.ID, record.Owner, record.Resource)
+		}
+		for _, record := range d.Added {
+			fmt.Fprintf(table, "added\t%s\t%s\t%s\t%s\t%s\n", record.Name, record.RecordType, record.ID, record.Owner, record.Resource)
+		}
+		for _, change := range d.Changed {
+			fmt.Fprintf(table, "changed\t%s\t%s\t%s\t%s -> %s\t%s -> %s\n", change.After.Name, change.After.RecordType, change.After.ID,
+				change.Before.Owner, change.After.Owner, change.Before.Resource, change.After.Resource)
+		}
+		fmt.Fprintf(table, "\nAdded: %d, removed: %d, changed: %d\n", len(d.Added), len(d.Removed), len(d.Changed))
+		return table.Flush()
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+// RestoreSnapshot rewrites registry records of the zones back to the snapshot contents. Records removed since
+// the snapshot are created again, records added since the snapshot are kept. Snapshot records of zones that
+// are not selected are skipped
+func (s *Selector) RestoreSnapshot(ctx context.Context, snapshot *Snapshot, zones []*registry.Zone) (changedRecords int, err error) {
+	live := NewSnapshot(zones, s.cfg, time.Now())
+	diff := DiffSnapshots(snapshot, live)
+
+	for _, change := range diff.Changed {
+		restored := change.Before.Record()
+		restored.ID = change.After.record.ID
+		restored.Compressed = change.After.record.Compressed
+		log.Infof("Restoring '%s' changed to '%s'", restored, change.After.record)
+		updates, err := s.updateRecord(ctx, change.After.zone, change.After.record, restored)
+		changedRecords += updates
+		if err != nil {
+			return changedRecords, err
+		}
+	}
+
+	for _, removed := range diff.Removed {
+		zone := findZoneByName(zones, removed.Zone)
+		if zone == nil {
+			log.Warnf("Registry record not restored. Zone '%s' is not selected. '%s'", removed.Zone, removed.Name)
+			continue
+		}
+		restored := removed.Record()
+		log.Infof("Restoring deleted registry record '%s'", restored)
+		creates, err := s.provider.CreateRegistryRecord(ctx, zone, restored)
+		changedRecords += creates
+		if err != nil {
+			return changedRecords, err
+		}
+	}
+
+	for _, added := range diff.Added {
+		log.Infof("Registry record created after the snapshot is kept '%s'", added.record)
+	}
+	return changedRecords, nil
+}
+
+func findZoneByName(zones []*registry.Zone, name string) *registry.Zone {
+	for _, zone := range zones {
+		if string(zone.Name) == name {
+			return zone
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var snapshotCfg = &Config{Registry: "txt", TXTPrefix: "edns-"}
+
+func createSnapshotZone() *registry.Zone {
+	zone := registry.NewZone("dummy.host")
+	for _, record := range []*registry.Record{
+		{Name: "edns-webserver.dummy.host", ID: "1", Owner: "cluster-1", Resource: testEndpointResource},
+		{Name: "edns-api.dummy.host", ID: "2", Owner: "cluster-1", Resource: "ingress/test/api"},
+		{Name: "edns-docs.dummy.host", ID: "3", Owner: "cluster-1", Resource: "ingress/test/docs", Labels: map[string]string{"team": "docs"}},
+	} {
+		zone.AddRegistryRecord(record)
+	}
+	return zone
+}
+
+func TestSnapshot_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := NewSnapshot([]*registry.Zone{createSnapshotZone()}, snapshotCfg, time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, []string{"edns-api.dummy.host", "edns-docs.dummy.host", "edns-webserver.dummy.host"},
+		[]string{snapshot.Records[0].Name, snapshot.Records[1].Name, snapshot.Records[2].Name}, "Records sorted by name")
+
+	assert.NoError(t, snapshot.WriteFile(path))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Snapshot readable by the current user only")
+	loaded, err := ReadSnapshot(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotVersion, loaded.Version)
+	assert.Equal(t, []string{"dummy.host"}, loaded.Zones)
+	assert.Equal(t, &SnapshotRecord{Zone: "dummy.host", ID: "3", Name: "edns-docs.dummy.host", Owner: "cluster-1", Resource: "ingress/test/docs", Labels: map[string]string{"team": "docs"}}, loaded.Records[1])
+	assert.True(t, DiffSnapshots(snapshot, loaded).IsEmpty())
+}
+
+func TestNewSnapshot_RecordType(t *testing.T) {
+	zone := registry.NewZone("dummy.host")
+	zone.AddRegistryRecord(&registry.Record{Name: "edns-webserver.dummy.host", Owner: "cluster-1"})
+	zone.AddRegistryRecord(&registry.Record{Name: "edns-aaaa-deleted.dummy.host", Owner: "cluster-1"})
+	zone.AddRegistryRecord(&registry.Record{Name: "edns-cname-api.dummy.host", RecordType: "CNAME", Owner: "cluster-1"})
+
+	snapshot := NewSnapshot([]*registry.Zone{zone}, snapshotCfg, time.Now())
+
+	var recordTypes []string
+	for _, record := range snapshot.Records {
+		recordTypes = append(recordTypes, record.Name+"/"+record.RecordType)
+	}
+	assert.Equal(t, []string{"edns-aaaa-deleted.dummy.host/AAAA", "edns-cname-api.dummy.host/CNAME", "edns-webserver.dummy.host/"}, recordTypes, "Record type of records without hosts taken from the name")
+}
+
+func TestNewSnapshot_Lease(t *testing.T) {
+	zone := createSnapshotZone()
+	zone.AddRegistryRecord(foreignLease("cluster-1-job", time.Now().Add(time.Minute)))
+
+	snapshot := NewSnapshot([]*registry.Zone{zone}, snapshotCfg, time.Now())
+	assert.Len(t, snapshot.Records, 3, "Lease is not captured")
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	base := NewSnapshot([]*registry.Zone{createSnapshotZone()}, snapshotCfg, time.Now())
+	targetZone := registry.NewZone("dummy.host")
+	targetZone.AddRegistryRecord(&registry.Record{Name: "edns-webserver.dummy.host", ID: "1", Owner: "cluster-2", Resource: testEndpointResource})
+	targetZone.AddRegistryRecord(&registry.Record{Name: "edns-api.dummy.host", ID: "20", Owner: "cluster-1", Resource: "ingress/test/api"})
+	targetZone.AddRegistryRecord(&registry.Record{Name: "edns-admin.dummy.host", ID: "4", Owner: "cluster-2", Resource: "ingress/test/admin"})
+	target := NewSnapshot([]*registry.Zone{targetZone}, snapshotCfg, time.Now())
+
+	diff := DiffSnapshots(base, target)
+
+	assert.Len(t, diff.Changed, 1)
+	assert.Equal(t, "edns-webserver.dummy.host", diff.Changed[0].After.Name)
+	assert.Equal(t, "cluster-2", diff.Changed[0].After.Owner)
+	assert.Equal(t, []*SnapshotRecord{base.Records[1]}, diff.Removed, "Removed docs record")
+	assert.Equal(t, []*SnapshotRecord{target.Records[0]}, diff.Added, "Added admin record, api record recreated with new id is matched by name")
+
+	var tableOutput bytes.Buffer
+	assert.NoError(t, diff.Write(&tableOutput, OutputTable))
+	assert.Contains(t, tableOutput.String(), "changed  edns-webserver.dummy.host        1   cluster-1 -> cluster-2  ingress/test/webserver -> ingress/test/webserver")
+	assert.Contains(t, tableOutput.String(), "Added: 1, removed: 1, changed: 1")
+	assert.Error(t, diff.Write(&tableOutput, OutputCSV))
+}
+
+func TestSelector_RestoreSnapshot(t *testing.T) {
+	snapshot := NewSnapshot([]*registry.Zone{createSnapshotZone()}, snapshotCfg, time.Now())
+	liveZone := registry.NewZone("dummy.host")
+	liveZone.AddRegistryRecord(&registry.Record{Name: "edns-webserver.dummy.host", ID: "1", Owner: "cluster-2", Resource: testEndpointResource})
+	liveZone.AddRegistryRecord(&registry.Record{Name: "edns-api.dummy.host", ID: "2", Owner: "cluster-1", Resource: "ingress/test/api"})
+	liveZone.AddRegistryRecord(&registry.Record{Name: "edns-admin.dummy.host", ID: "4", Owner: "cluster-2", Resource: "ingress/test/admin"})
+	testProvider := &mockProvider{}
+	testProvider.On("UpdateRegistryRecord", mock.Anything, liveZone, &registry.Record{Name: "edns-webserver.dummy.host", ID: "1", Owner: "cluster-1", Resource: testEndpointResource}).Return(1, nil)
+	testProvider.On("CreateRegistryRecord", mock.Anything, liveZone, &registry.Record{Name: "edns-docs.dummy.host", ID: "3", Owner: "cluster-1", Resource: "ingress/test/docs", Labels: map[string]string{"team": "docs"}}).Return(1, nil)
+
+	changedRecords, err := NewSelector(cfg, testProvider).RestoreSnapshot(context.Background(), snapshot, []*registry.Zone{liveZone})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, changedRecords)
+	testProvider.AssertExpectations(t)
+}
+
+func TestSelector_RestoreSnapshot_SkipsZonesNotSelected(t *testing.T) {
+	snapshot := NewSnapshot([]*registry.Zone{createSnapshotZone()}, snapshotCfg, time.Now())
+	testProvider := &mockProvider{}
+
+	changedRecords, err := NewSelector(cfg, testProvider).RestoreSnapshot(context.Background(), snapshot, []*registry.Zone{registry.NewZone("other.host")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, changedRecords)
+	testProvider.AssertNotCalled(t, "CreateRegistryRecord", mock.Anything, mock.Anything, mock.Anything)
+}
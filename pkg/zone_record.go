@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"path"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// ZoneRecord is a registry record together with the zone it was found in
+type ZoneRecord struct {
+	Zone   *registry.Zone
+	Record *registry.Record
+}
+
+// LogFields returns structured fields describing the record
+func (r *ZoneRecord) LogFields() log.Fields {
+	return log.Fields{
+		"zone":     string(r.Zone.Name),
+		"name":     string(r.Record.Name),
+		"type":     r.Record.RecordType,
+		"owner":    r.Record.Owner,
+		"resource": r.Record.Resource,
+	}
+}
+
+// selectZoneRecords returns distinct registry records of the zones accepted by the predicate, filtered by
+// --host-regex and --resource-pattern. Records without hosts are selected only when host filter is not configured,
+// lock leases are never selected
+func (s *Selector) selectZoneRecords(zones []*registry.Zone, accept func(record *registry.Record) bool) []*ZoneRecord {
+	isSelected := func(record *registry.Record) bool {
+		if IsLease(record) {
+			return false
+		}
+		if s.cfg.ResourcePattern != "" {
+			if matched, _ := path.Match(s.cfg.ResourcePattern, record.Resource); !matched {
+				return false
+			}
+		}
+		return accept(record)
+	}
+
+	selectedRecords := make([]*ZoneRecord, 0)
+	for _, zone := range zones {
+		var hostRecords []*registry.Record
+		for _, host := range zone.Hosts {
+			if s.cfg.HostRegex == nil || s.cfg.HostRegex.MatchString(string(host.Name)) {
+				hostRecords = append(hostRecords, host.RegistryRecords...)
+			}
+		}
+		for _, record := range registry.UniqueRecords(hostRecords) {
+			if isSelected(record) {
+				selectedRecords = append(selectedRecords, &ZoneRecord{Zone: zone, Record: record})
+			}
+		}
+		if s.cfg.HostRegex != nil {
+			continue
+		}
+		for _, record := range zone.OrphanRecords(s.cfg.Matcher()) {
+			if isSelected(record) {
+				selectedRecords = append(selectedRecords, &ZoneRecord{Zone: zone, Record: record})
+			}
+		}
+	}
+	return selectedRecords
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// ErrRecordChanged is returned by conditional updates when registry record no longer has owner and resource
+// it was read with, e.g. External DNS rewrote it after zones were read
+var ErrRecordChanged = errors.New("registry record changed since it was read")
+
+// ConditionalUpdater is implemented by providers and registries able to update registry record only if it still has
+// owner and resource of the expected record, using native preconditions or reading the record right before the update
+type ConditionalUpdater interface {
+	UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (updatedRecords int, err error)
+}
+
+// UpdateRegistryRecordIf updates registry record only if it still has owner and resource of the expected record.
+// Providers without conditional updates update the record unconditionally
+func UpdateRegistryRecordIf(ctx context.Context, p Provider, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	if updater, ok := p.(ConditionalUpdater); ok {
+		return updater.UpdateRegistryRecordIf(ctx, zone, expected, record)
+	}
+	return p.UpdateRegistryRecord(ctx, zone, record)
+}
+
+// IsRecordUnchanged compares owner and resource of the registry record read right before the update with the expected record
+func IsRecordUnchanged(expected *registry.Record, current *registry.Record) bool {
+	return current != nil && current.Owner == expected.Owner && current.Resource == expected.Resource
+}
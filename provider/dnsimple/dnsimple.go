@@ -2,6 +2,7 @@ package dnsimple
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/dnsimple/dnsimple-go/dnsimple"
 	"github.com/matic-insurance/dns-tager/pkg"
@@ -9,6 +10,7 @@ import (
 	"github.com/matic-insurance/dns-tager/registry"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -21,12 +23,16 @@ type dnsimpleProvider struct {
 	identity  *dnsimple.IdentityService
 	accountID string
 	zones     []string
+	encryptor *registry.Encryptor
 }
 
 type dnsimpleZoneServiceApi interface {
 	ListZones(ctx context.Context, accountID string, options *dnsimple.ZoneListOptions) (*dnsimple.ZonesResponse, error)
 	ListRecords(ctx context.Context, accountID string, zoneID string, options *dnsimple.ZoneRecordListOptions) (*dnsimple.ZoneRecordsResponse, error)
+	GetRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error)
+	CreateRecord(ctx context.Context, accountID string, zoneID string, recordAttributes dnsimple.ZoneRecordAttributes) (*dnsimple.ZoneRecordResponse, error)
 	UpdateRecord(ctx context.Context, accountID string, zoneID string, recordID int64, recordAttributes dnsimple.ZoneRecordAttributes) (*dnsimple.ZoneRecordResponse, error)
+	DeleteRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error)
 }
 
 func (p dnsimpleProvider) Whoami(_ context.Context) string {
@@ -45,11 +51,17 @@ func NewDnsimpleProvider(cfg *pkg.Config, zones []string) (provider.Provider, er
 	client := dnsimple.NewClient(tc)
 	client.SetUserAgent(fmt.Sprintf("Kubernetes ExternalDNS Dialer"))
 
+	encryptor, err := cfg.Encryptor()
+	if err != nil {
+		return nil, err
+	}
+
 	providerInstance := &dnsimpleProvider{
-		cfg:      cfg,
-		client:   client.Zones,
-		identity: client.Identity,
-		zones:    zones,
+		cfg:       cfg,
+		client:    newRetryingZoneService(client.Zones),
+		identity:  client.Identity,
+		zones:     zones,
+		encryptor: encryptor,
 	}
 
 	if cfg.AccountId != "" {
@@ -70,29 +82,43 @@ func NewDnsimpleProvider(cfg *pkg.Config, zones []string) (provider.Provider, er
 	return providerInstance, nil
 }
 
-func (p dnsimpleProvider) ReadZones(ctx context.Context) ([]*registry.Zone, error) {
+func (p dnsimpleProvider) ReadZones(ctx context.Context, matcher *registry.Matcher) ([]*registry.Zone, error) {
 	zones := make([]*registry.Zone, 0)
 	for _, zone := range p.zones {
 		currentZone := registry.NewZone(zone)
 		hostRecords := make([]*registry.Host, 0)
-		registryRecords := make([]*registry.Record, 0)
+		recordSets := make(map[string]*registry.Host)
+		registryRecords := make(map[registry.Hostname][]*registry.Record)
 		page := 1
 		listOptions := &dnsimple.ZoneRecordListOptions{}
 		for {
 			listOptions.ListOptions.Page = &page
-			dnsRecords, err := p.client.ListRecords(ctx, p.accountID, zone, listOptions)
+			dnsRecords, err := p.client.ListRecords(ctx, p.accountID, string(currentZone.Name), listOptions)
 			if err != nil {
 				return nil, err
 			}
 			for _, dnsRecord := range dnsRecords.Data {
-				name := fmt.Sprintf("%s.%s", dnsRecord.Name, dnsRecord.ZoneID)
+				name := recordName(dnsRecord)
 				if currentZone.IsRegistryRecordType(dnsRecord.Type) {
-					info := strings.Trim(dnsRecord.Content, "\"")
-					if strings.HasPrefix(info, registry.ExternalDnsIdentifier) {
-						registryRecords = append(registryRecords, registry.NewRecord(name, info))
+					if registryRecord := registry.ParseRecord(name, dnsRecord.Content, p.encryptor); registryRecord != nil {
+						if dnsRecord.ID != 0 {
+							registryRecord.ID = int64ToString(dnsRecord.ID)
+						}
+						registryRecords[registryRecord.Name] = append(registryRecords[registryRecord.Name], registryRecord)
+						currentZone.AddRegistryRecord(registryRecord)
+					} else {
+						currentZone.AddName(registry.NewHostname(name))
+					}
+				} else if !matcher.IsHostRecordType(dnsRecord.Type) {
+					currentZone.AddName(registry.NewHostname(name))
+				} else {
+					recordSetKey := name + "#" + dnsRecord.Type
+					if recordSet, ok := recordSets[recordSetKey]; ok {
+						recordSet.AddValue(recordValue(dnsRecord))
+					} else {
+						recordSets[recordSetKey] = registry.NewHost(name, dnsRecord.Type, recordValue(dnsRecord))
+						hostRecords = append(hostRecords, recordSets[recordSetKey])
 					}
-				} else if currentZone.IsHostRecordType(dnsRecord.Type) {
-					hostRecords = append(hostRecords, registry.NewHost(name, dnsRecord.Type, dnsRecord.Content))
 				}
 			}
 			page++
@@ -102,8 +128,14 @@ func (p dnsimpleProvider) ReadZones(ctx context.Context) ([]*registry.Zone, erro
 		}
 
 		for _, hostRecord := range hostRecords {
-			for _, registryRecord := range registryRecords {
-				if registryRecord.IsManaging(hostRecord) {
+			for _, registryName := range matcher.LegacyNames(currentZone, hostRecord) {
+				for _, registryRecord := range registryRecords[registryName] {
+					hostRecord.AddRegistryRecord(registryRecord)
+				}
+			}
+			for _, registryName := range matcher.RecordTypeNames(currentZone, hostRecord) {
+				for _, registryRecord := range registryRecords[registryName] {
+					registryRecord.RecordType = hostRecord.RecordType
 					hostRecord.AddRegistryRecord(registryRecord)
 				}
 			}
@@ -116,39 +148,113 @@ func (p dnsimpleProvider) ReadZones(ctx context.Context) ([]*registry.Zone, erro
 
 func (p dnsimpleProvider) UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
 	if p.cfg.Apply {
-		recordID, err := p.getRecordID(ctx, zone, record.Name)
+		recordID, err := p.registryRecordID(ctx, zone, record)
 		if err != nil {
 			return 0, err
 		}
-		_, err = p.client.UpdateRecord(ctx, p.accountID, zone.Name, recordID, dnsimple.ZoneRecordAttributes{Content: record.Info()})
-		if err != nil {
-			return 0, err
-		}
-		return 1, nil
+		return p.updateRecord(ctx, zone, recordID, record)
 	} else {
 		log.Infof("Dry Run: Updated %s registry value to %s", record.Name, record.Info())
 		return 1, nil
 	}
 }
 
-func (p dnsimpleProvider) getRecordID(ctx context.Context, zone *registry.Zone, recordName string) (recordID int64, err error) {
-	page := 1
-	if recordName == zone.Name {
-		recordName = "" // Apex records have an empty name
-	} else {
-		recordName = strings.TrimSuffix(recordName, fmt.Sprintf(".%s", zone.Name))
+// UpdateRegistryRecordIf reads the record right before the update, DNSimple API has no conditional record updates
+func (p dnsimpleProvider) UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	if !p.cfg.Apply {
+		return p.UpdateRegistryRecord(ctx, zone, record)
+	}
+	recordID, err := p.registryRecordID(ctx, zone, expected)
+	if err != nil {
+		return 0, err
+	}
+	response, err := p.client.GetRecord(ctx, p.accountID, string(zone.Name), recordID)
+	var errorResponse *dnsimple.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.HTTPResponse != nil && errorResponse.HTTPResponse.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("%w: record %d not found", provider.ErrRecordChanged, recordID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	var current *registry.Record
+	if response != nil && response.Data != nil {
+		current = registry.ParseRecord(string(expected.Name), response.Data.Content, p.encryptor)
+	}
+	if !provider.IsRecordUnchanged(expected, current) {
+		return 0, provider.ErrRecordChanged
+	}
+	return p.updateRecord(ctx, zone, recordID, record)
+}
+
+func (p dnsimpleProvider) updateRecord(ctx context.Context, zone *registry.Zone, recordID int64, record *registry.Record) (int, error) {
+	content, err := record.Content(p.encryptor)
+	if err != nil {
+		return 0, err
+	}
+	_, err = p.client.UpdateRecord(ctx, p.accountID, string(zone.Name), recordID, dnsimple.ZoneRecordAttributes{Content: content})
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (p dnsimpleProvider) CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	if !p.cfg.Apply {
+		log.Infof("Dry Run: Created %s registry record %s", record.Name, record.Info())
+		return 1, nil
+	}
+	content, err := record.Content(p.encryptor)
+	if err != nil {
+		return 0, err
 	}
+	name := relativeName(zone, record.Name)
+	response, err := p.client.CreateRecord(ctx, p.accountID, string(zone.Name), dnsimple.ZoneRecordAttributes{Name: &name, Type: registry.RegistryRecordType, Content: content})
+	if err != nil {
+		return 0, err
+	}
+	if response != nil && response.Data != nil {
+		record.ID = int64ToString(response.Data.ID)
+	}
+	return 1, nil
+}
 
+func (p dnsimpleProvider) DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	if !p.cfg.Apply {
+		log.Infof("Dry Run: Deleted %s registry record %s", record.Name, record.Info())
+		return 1, nil
+	}
+	recordID, err := p.registryRecordID(ctx, zone, record)
+	if err != nil {
+		return 0, err
+	}
+	_, err = p.client.DeleteRecord(ctx, p.accountID, string(zone.Name), recordID)
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// registryRecordID returns DNSimple id the record was read with, looking it up by name otherwise
+func (p dnsimpleProvider) registryRecordID(ctx context.Context, zone *registry.Zone, record *registry.Record) (int64, error) {
+	if record.ID != "" {
+		return strconv.ParseInt(record.ID, 10, 64)
+	}
+	return p.getRecordID(ctx, zone, record.Name)
+}
+
+func (p dnsimpleProvider) getRecordID(ctx context.Context, zone *registry.Zone, name registry.Hostname) (recordID int64, err error) {
+	page := 1
+	recordName := relativeName(zone, name)
 	listOptions := &dnsimple.ZoneRecordListOptions{Name: &recordName}
 	for {
 		listOptions.Page = &page
-		records, err := p.client.ListRecords(ctx, p.accountID, zone.Name, listOptions)
+		records, err := p.client.ListRecords(ctx, p.accountID, string(zone.Name), listOptions)
 		if err != nil {
 			return 0, err
 		}
 
 		for _, record := range records.Data {
-			if record.Name == recordName {
+			if registry.NewHostname(record.Name) == registry.Hostname(recordName) {
 				return record.ID, nil
 			}
 		}
@@ -161,6 +267,32 @@ func (p dnsimpleProvider) getRecordID(ctx context.Context, zone *registry.Zone,
 	return 0, fmt.Errorf("no record id found")
 }
 
+// recordName returns fully qualified name of the record. Apex records have an empty name
+func recordName(record dnsimple.ZoneRecord) string {
+	if record.Name == "" {
+		return record.ZoneID
+	}
+	return fmt.Sprintf("%s.%s", record.Name, record.ZoneID)
+}
+
+// relativeName returns record name relative to the zone as DNSimple expects it. Apex records have an empty name
+func relativeName(zone *registry.Zone, name registry.Hostname) string {
+	if name == zone.Name {
+		return ""
+	}
+	return strings.TrimSuffix(string(name), fmt.Sprintf(".%s", zone.Name))
+}
+
+// recordValue returns record content, prefixed with priority for record types that have it
+func recordValue(record dnsimple.ZoneRecord) string {
+	switch record.Type {
+	case "MX", "SRV":
+		return fmt.Sprintf("%d %s", record.Priority, record.Content)
+	default:
+		return record.Content
+	}
+}
+
 func int64ToString(i int64) string {
 	return strconv.FormatInt(i, 10)
 }
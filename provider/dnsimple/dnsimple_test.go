@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/dnsimple/dnsimple-go/dnsimple"
 	"github.com/matic-insurance/dns-tager/pkg"
+	"github.com/matic-insurance/dns-tager/provider"
 	"github.com/matic-insurance/dns-tager/registry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,6 +29,76 @@ func TestDnsimpleProvider(t *testing.T) {
 	t.Run("UpdateRegistryRecord_NoApply", testDnsimpleProviderUpdateRegistryRecord_NoApply)
 }
 
+func TestDnsimpleProvider_ReadZones(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	readProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{}, zones: []string{string(zone.Name)}}
+	dnsimpleRecords := []dnsimple.ZoneRecord{
+		{ZoneID: "dummy.host", Name: "webserver", Type: "A", Content: "127.0.0.1"},
+		{ZoneID: "dummy.host", Name: "webserver", Type: "A", Content: "127.0.0.2"},
+		{ZoneID: "dummy.host", Name: "webserver", Type: "A", Content: "127.0.0.3"},
+		{ZoneID: "dummy.host", Name: "_extdns.webserver", Type: "TXT", Content: "\"heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=ingress/test/webserver\""},
+		{ZoneID: "dummy.host", ID: 42, Name: "_extdns.a-webserver", Type: "TXT", Content: "heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=ingress/test/webserver"},
+		{ZoneID: "dummy.host", Name: "webserver-registry", Type: "TXT", Content: "heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=ingress/test/webserver"},
+		{ZoneID: "dummy.host", Name: "_extdns.webserver", Type: "TXT", Content: "v=spf1 -all"},
+		{ZoneID: "dummy.host", Name: "", Type: "ALIAS", Content: "webserver.dummy.host"},
+		{ZoneID: "dummy.host", Name: "", Type: "A", Content: "127.0.0.1"},
+		{ZoneID: "dummy.host", Name: "_extdns", Type: "TXT", Content: "heritage=external-dns,external-dns/owner=cluster-2,external-dns/resource=ingress/test/apex"},
+		{ZoneID: "dummy.host", Name: "mail", Type: "MX", Content: "mx1.dummy.host", Priority: 10},
+		{ZoneID: "dummy.host", Name: "_extdns.mx-mail", Type: "TXT", Content: "heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=crd/test/mail"},
+	}
+	api.On("ListRecords", context.Background(), "123", string(zone.Name), mock.Anything).Return(dnsimpleZoneResponse(dnsimpleRecords), nil)
+
+	zones, err := readProvider.ReadZones(context.Background(), registry.NewMatcher("_extdns.", "", ""))
+
+	assert.NoError(t, err)
+	assert.Len(t, zones, 1)
+	assert.Len(t, zones[0].Hosts, 2)
+	host := zones[0].Hosts[0]
+	assert.Equal(t, registry.Hostname("webserver.dummy.host"), host.Name)
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}, host.Values, "Values grouped into record set")
+	assert.Len(t, host.RegistryRecords, 2)
+	assert.Equal(t, registry.Hostname("_extdns.webserver.dummy.host"), host.RegistryRecords[0].Name)
+	assert.Equal(t, "cluster-1", host.RegistryRecords[0].Owner)
+	assert.Equal(t, registry.Hostname("_extdns.a-webserver.dummy.host"), host.RegistryRecords[1].Name)
+	assert.Equal(t, "", host.RegistryRecords[0].RecordType, "Legacy record is not bound to record type")
+	assert.Equal(t, "A", host.RegistryRecords[1].RecordType, "New format record carries record type")
+	assert.Equal(t, "42", host.RegistryRecords[1].ID, "Record carries DNSimple id")
+	apex := zones[0].Hosts[1]
+	assert.Equal(t, registry.Hostname("dummy.host"), apex.Name)
+	assert.Len(t, apex.RegistryRecords, 1)
+	assert.Equal(t, "cluster-2", apex.RegistryRecords[0].Owner)
+	orphans := zones[0].OrphanRecords(registry.NewMatcher("_extdns.", "", ""))
+	assert.Len(t, orphans, 1, "Registry records without hosts are kept in the zone, records of MX host are not orphans")
+	assert.Equal(t, registry.Hostname("webserver-registry.dummy.host"), orphans[0].Name)
+}
+
+func TestDnsimpleProvider_ReadZones_ManagedRecordTypes(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	readProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{}, zones: []string{string(zone.Name)}}
+	dnsimpleRecords := []dnsimple.ZoneRecord{
+		{ZoneID: "dummy.host", Name: "", Type: "ALIAS", Content: "webserver.dummy.host"},
+		{ZoneID: "dummy.host", Name: "", Type: "MX", Content: "mx1.dummy.host", Priority: 10},
+		{ZoneID: "dummy.host", Name: "", Type: "MX", Content: "mx2.dummy.host", Priority: 20},
+		{ZoneID: "dummy.host", Name: "webserver", Type: "CNAME", Content: "lb.dummy.host"},
+		{ZoneID: "dummy.host", Name: "edns-alias-", Type: "TXT", Content: "heritage=external-dns,external-dns/owner=cluster-1,external-dns/resource=ingress/test/apex"},
+	}
+	api.On("ListRecords", context.Background(), "123", string(zone.Name), mock.Anything).Return(dnsimpleZoneResponse(dnsimpleRecords), nil)
+	matcher := registry.NewMatcher("edns-", "", "")
+	matcher.RecordTypes = []string{"ALIAS", "MX"}
+
+	zones, err := readProvider.ReadZones(context.Background(), matcher)
+
+	assert.NoError(t, err)
+	assert.Len(t, zones[0].Hosts, 2, "Only configured record types are hosts")
+	alias := zones[0].Hosts[0]
+	assert.Equal(t, "ALIAS", alias.RecordType)
+	assert.Len(t, alias.RegistryRecords, 1)
+	assert.Equal(t, "ALIAS", alias.RegistryRecords[0].RecordType)
+	mx := zones[0].Hosts[1]
+	assert.Equal(t, []string{"10 mx1.dummy.host", "20 mx2.dummy.host"}, mx.Values)
+	assert.False(t, mx.IsManaged())
+}
+
 func testDnsimpleProviderUpdateRegistryRecord_NoApply(t *testing.T) {
 	testProvider.cfg.Apply = false
 	record := &registry.Record{Name: "webserver.dummy.host", Owner: "cluster-1", Resource: "ingress/test/webserver"}
@@ -41,8 +112,8 @@ func testDnsimpleProviderUpdateRegistryRecord_Success(t *testing.T) {
 	record := &registry.Record{Name: "webserver.dummy.host", Owner: "cluster-1", Resource: "ingress/test/webserver"}
 
 	dnsimpleRecords := []dnsimple.ZoneRecord{{ID: 234, Name: "webserver"}}
-	testApi.On("ListRecords", context.Background(), "123", zone.Name, mock.Anything).Return(dnsimpleZoneResponse(dnsimpleRecords), nil)
-	testApi.On("UpdateRecord", context.Background(), "123", zone.Name, dnsimpleRecords[0].ID, dnsimple.ZoneRecordAttributes{Content: record.Info()}).Return(&dnsimple.ZoneRecordResponse{}, nil)
+	testApi.On("ListRecords", context.Background(), "123", string(zone.Name), mock.Anything).Return(dnsimpleZoneResponse(dnsimpleRecords), nil)
+	testApi.On("UpdateRecord", context.Background(), "123", string(zone.Name), dnsimpleRecords[0].ID, dnsimple.ZoneRecordAttributes{Content: record.Info()}).Return(&dnsimple.ZoneRecordResponse{}, nil)
 
 	updates, err := testProvider.UpdateRegistryRecord(context.Background(), zone, record)
 
@@ -50,6 +121,113 @@ func testDnsimpleProviderUpdateRegistryRecord_Success(t *testing.T) {
 	assert.Equal(t, 1, updates, "Correct updates count returned")
 }
 
+func TestDnsimpleProvider_UpdateRegistryRecord_ByID(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	idProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{Apply: true}}
+	record := &registry.Record{Name: "webserver.dummy.host", ID: "234", Owner: "cluster-1", Resource: "ingress/test/webserver"}
+	api.On("UpdateRecord", context.Background(), "123", string(zone.Name), int64(234), dnsimple.ZoneRecordAttributes{Content: record.Info()}).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	updates, err := idProvider.UpdateRegistryRecord(context.Background(), zone, record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Correct updates count returned")
+	api.AssertNotCalled(t, "ListRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDnsimpleProvider_UpdateRegistryRecordIf(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	conditionalProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{Apply: true}}
+	expected := &registry.Record{Name: "webserver.dummy.host", ID: "234", Owner: "cluster-1", Resource: "ingress/test/webserver"}
+	changed := &registry.Record{Name: "admin.dummy.host", ID: "345", Owner: "cluster-1", Resource: "ingress/test/admin"}
+	api.On("GetRecord", context.Background(), "123", string(zone.Name), int64(234)).Return(&dnsimple.ZoneRecordResponse{Data: &dnsimple.ZoneRecord{ID: 234, Content: expected.Info()}}, nil)
+	api.On("GetRecord", context.Background(), "123", string(zone.Name), int64(345)).Return(&dnsimple.ZoneRecordResponse{Data: &dnsimple.ZoneRecord{ID: 345, Content: changed.NewRecord("cluster-0", changed.Resource).Info()}}, nil)
+	record := expected.NewRecord("cluster-2", expected.Resource)
+	api.On("UpdateRecord", context.Background(), "123", string(zone.Name), int64(234), dnsimple.ZoneRecordAttributes{Content: record.Info()}).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	updates, err := conditionalProvider.UpdateRegistryRecordIf(context.Background(), zone, expected, record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Unchanged record updated")
+
+	updates, err = conditionalProvider.UpdateRegistryRecordIf(context.Background(), zone, changed, changed.NewRecord("cluster-2", changed.Resource))
+	assert.ErrorIs(t, err, provider.ErrRecordChanged)
+	assert.Equal(t, 0, updates, "Record rewritten by another owner is not updated")
+	api.AssertNumberOfCalls(t, "UpdateRecord", 1)
+}
+
+func TestDnsimpleProvider_CreateRegistryRecord(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	createProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{Apply: true}}
+	record := &registry.Record{Name: "edns-a-webserver.dummy.host", RecordType: "A", Owner: "cluster-1", Resource: "ingress/test/webserver"}
+	apexRecord := &registry.Record{Name: "dummy.host", Owner: "cluster-1", Resource: "ingress/test/apex"}
+	name, apexName := "edns-a-webserver", ""
+	api.On("CreateRecord", context.Background(), "123", string(zone.Name), dnsimple.ZoneRecordAttributes{Name: &name, Type: "TXT", Content: record.Info()}).Return(&dnsimple.ZoneRecordResponse{Data: &dnsimple.ZoneRecord{ID: 345}}, nil)
+	api.On("CreateRecord", context.Background(), "123", string(zone.Name), dnsimple.ZoneRecordAttributes{Name: &apexName, Type: "TXT", Content: apexRecord.Info()}).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	creates, err := createProvider.CreateRegistryRecord(context.Background(), zone, record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, creates, "Correct creates count returned")
+	assert.Equal(t, "345", record.ID, "Created record id is kept")
+
+	creates, err = createProvider.CreateRegistryRecord(context.Background(), zone, apexRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, creates, "Apex record created with empty name")
+}
+
+func TestDnsimpleProvider_AdoptApex(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	adoptCfg := &pkg.Config{Apply: true, CurrentOwnerID: "cluster-1", TXTPrefix: "edns-"}
+	adoptProvider := dnsimpleProvider{client: api, accountID: "123", cfg: adoptCfg}
+	apexZone := registry.NewZone("dummy.host")
+	apexZone.AddHost(registry.NewHost("dummy.host", "A", "127.0.0.1"))
+	var names []string
+	api.On("CreateRecord", context.Background(), "123", "dummy.host", mock.Anything).Run(func(args mock.Arguments) {
+		names = append(names, *args.Get(3).(dnsimple.ZoneRecordAttributes).Name)
+	}).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	endpoints := []*registry.Endpoint{{Host: "dummy.host", Resource: "ingress/test/apex"}}
+	created, err := pkg.NewSelector(adoptCfg, adoptProvider).AdoptEndpoints(context.Background(), endpoints, []*registry.Zone{apexZone})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created)
+	assert.Equal(t, []string{"edns-", "edns-a-"}, names, "Apex registry records named relative to the zone")
+}
+
+func TestDnsimpleProvider_DeleteRegistryRecord(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	deleteProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{Apply: true}}
+	record := &registry.Record{Name: "webserver.dummy.host", ID: "234", Owner: "cluster-1"}
+	api.On("DeleteRecord", context.Background(), "123", string(zone.Name), int64(234)).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	deletes, err := deleteProvider.DeleteRegistryRecord(context.Background(), zone, record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletes, "Correct deletes count returned")
+
+	deleteProvider.cfg.Apply = false
+	deletes, err = deleteProvider.DeleteRegistryRecord(context.Background(), zone, record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletes, "Dry run deletes count returned")
+	api.AssertNumberOfCalls(t, "DeleteRecord", 1)
+}
+
+func TestDnsimpleProvider_UpdateEncryptedRegistryRecord(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	encryptor, _ := registry.NewEncryptor("0123456789abcdef0123456789abcdef")
+	encryptedProvider := dnsimpleProvider{client: api, accountID: "123", cfg: &pkg.Config{Apply: true}, encryptor: encryptor}
+	record := &registry.Record{Name: "webserver.dummy.host", Owner: "cluster-1", Resource: "ingress/test/webserver", Encrypted: true}
+
+	dnsimpleRecords := []dnsimple.ZoneRecord{{ID: 234, Name: "webserver"}}
+	api.On("ListRecords", context.Background(), "123", string(zone.Name), mock.Anything).Return(dnsimpleZoneResponse(dnsimpleRecords), nil)
+	api.On("UpdateRecord", context.Background(), "123", string(zone.Name), dnsimpleRecords[0].ID, mock.MatchedBy(func(attributes dnsimple.ZoneRecordAttributes) bool {
+		decrypted, _, err := encryptor.Decrypt(attributes.Content)
+		return err == nil && decrypted == record.Info()
+	})).Return(&dnsimple.ZoneRecordResponse{}, nil)
+
+	updates, err := encryptedProvider.UpdateRegistryRecord(context.Background(), zone, record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Correct updates count returned")
+}
+
 func dnsimpleZoneResponse(records []dnsimple.ZoneRecord) *dnsimple.ZoneRecordsResponse {
 	return &dnsimple.ZoneRecordsResponse{Data: records, Response: dnsimple.Response{Pagination: &dnsimple.Pagination{}}}
 }
@@ -65,6 +243,17 @@ func (_m *mockDnsimpleZoneServiceInterface) ListRecords(ctx context.Context, acc
 	return r0, args.Error(1)
 }
 
+func (_m *mockDnsimpleZoneServiceInterface) GetRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error) {
+	args := _m.Called(ctx, accountID, zoneID, recordID)
+	var r0 *dnsimple.ZoneRecordResponse
+
+	if args.Get(0) != nil {
+		r0 = args.Get(0).(*dnsimple.ZoneRecordResponse)
+	}
+
+	return r0, args.Error(1)
+}
+
 func (_m *mockDnsimpleZoneServiceInterface) ListZones(ctx context.Context, accountID string, options *dnsimple.ZoneListOptions) (*dnsimple.ZonesResponse, error) {
 	args := _m.Called(ctx, accountID, options)
 	var r0 *dnsimple.ZonesResponse
@@ -86,3 +275,25 @@ func (_m *mockDnsimpleZoneServiceInterface) UpdateRecord(ctx context.Context, ac
 
 	return r0, args.Error(1)
 }
+
+func (_m *mockDnsimpleZoneServiceInterface) DeleteRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error) {
+	args := _m.Called(ctx, accountID, zoneID, recordID)
+	var r0 *dnsimple.ZoneRecordResponse
+
+	if args.Get(0) != nil {
+		r0 = args.Get(0).(*dnsimple.ZoneRecordResponse)
+	}
+
+	return r0, args.Error(1)
+}
+
+func (_m *mockDnsimpleZoneServiceInterface) CreateRecord(ctx context.Context, accountID string, zoneID string, recordAttributes dnsimple.ZoneRecordAttributes) (*dnsimple.ZoneRecordResponse, error) {
+	args := _m.Called(ctx, accountID, zoneID, recordAttributes)
+	var r0 *dnsimple.ZoneRecordResponse
+
+	if args.Get(0) != nil {
+		r0 = args.Get(0).(*dnsimple.ZoneRecordResponse)
+	}
+
+	return r0, args.Error(1)
+}
@@ -0,0 +1,115 @@
+package dnsimple
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dnsimple/dnsimple-go/dnsimple"
+	log "github.com/sirupsen/logrus"
+)
+
+// Retries of requests throttled by DNSimple
+const (
+	maxRetryAttempts = 5
+	minRetryDelay    = time.Second
+	maxRetryDelay    = 5 * time.Minute
+)
+
+// retryingZoneService retries requests rejected with 429 Too Many Requests. It waits until the rate limit window
+// is reset when DNSimple reports it, or backs off exponentially otherwise
+type retryingZoneService struct {
+	api       dnsimpleZoneServiceApi
+	attempts  int
+	baseDelay time.Duration
+	now       func() time.Time
+}
+
+func newRetryingZoneService(api dnsimpleZoneServiceApi) *retryingZoneService {
+	return &retryingZoneService{api: api, attempts: maxRetryAttempts, baseDelay: minRetryDelay, now: time.Now}
+}
+
+func (s *retryingZoneService) ListZones(ctx context.Context, accountID string, options *dnsimple.ZoneListOptions) (*dnsimple.ZonesResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZonesResponse, error) {
+		return s.api.ListZones(ctx, accountID, options)
+	})
+}
+
+func (s *retryingZoneService) ListRecords(ctx context.Context, accountID string, zoneID string, options *dnsimple.ZoneRecordListOptions) (*dnsimple.ZoneRecordsResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZoneRecordsResponse, error) {
+		return s.api.ListRecords(ctx, accountID, zoneID, options)
+	})
+}
+
+func (s *retryingZoneService) GetRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZoneRecordResponse, error) {
+		return s.api.GetRecord(ctx, accountID, zoneID, recordID)
+	})
+}
+
+func (s *retryingZoneService) CreateRecord(ctx context.Context, accountID string, zoneID string, recordAttributes dnsimple.ZoneRecordAttributes) (*dnsimple.ZoneRecordResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZoneRecordResponse, error) {
+		return s.api.CreateRecord(ctx, accountID, zoneID, recordAttributes)
+	})
+}
+
+func (s *retryingZoneService) UpdateRecord(ctx context.Context, accountID string, zoneID string, recordID int64, recordAttributes dnsimple.ZoneRecordAttributes) (*dnsimple.ZoneRecordResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZoneRecordResponse, error) {
+		return s.api.UpdateRecord(ctx, accountID, zoneID, recordID, recordAttributes)
+	})
+}
+
+func (s *retryingZoneService) DeleteRecord(ctx context.Context, accountID string, zoneID string, recordID int64) (*dnsimple.ZoneRecordResponse, error) {
+	return withRetry(ctx, s, func() (*dnsimple.ZoneRecordResponse, error) {
+		return s.api.DeleteRecord(ctx, accountID, zoneID, recordID)
+	})
+}
+
+// withRetry calls the request until it is not throttled, attempts are exhausted or context is canceled
+func withRetry[T any](ctx context.Context, s *retryingZoneService, request func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := request()
+		throttled, ok := throttledResponse(err)
+		if !ok || attempt >= s.attempts {
+			return response, err
+		}
+		delay := s.retryDelay(throttled, attempt)
+		log.Warnf("DNSimple rate limit exceeded, retrying in %s (attempt %d of %d)", delay, attempt+1, s.attempts)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay waits until reset of the rate limit window, doubling base delay from attempt to attempt when
+// DNSimple doesn't report it. Delay is capped so throttled runs still report progress
+func (s *retryingZoneService) retryDelay(throttled *http.Response, attempt int) time.Duration {
+	delay := s.baseDelay << (attempt - 1)
+	if seconds, err := strconv.Atoi(throttled.Header.Get("Retry-After")); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if reset, err := strconv.ParseInt(throttled.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		delay = time.Unix(reset, 0).Sub(s.now())
+	}
+	if delay < s.baseDelay {
+		delay = s.baseDelay
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// throttledResponse returns HTTP response of the error when DNSimple rejected the request with 429 Too Many Requests
+func throttledResponse(err error) (*http.Response, bool) {
+	var errorResponse *dnsimple.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.HTTPResponse != nil && errorResponse.HTTPResponse.StatusCode == http.StatusTooManyRequests {
+		return errorResponse.HTTPResponse, true
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package dnsimple
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dnsimple/dnsimple-go/dnsimple"
+	"github.com/stretchr/testify/assert"
+)
+
+func throttledError(header http.Header) error {
+	return &dnsimple.ErrorResponse{Response: dnsimple.Response{HTTPResponse: &http.Response{StatusCode: http.StatusTooManyRequests, Header: header}}, Message: "rate limit exceeded"}
+}
+
+func newTestRetryingZoneService(api dnsimpleZoneServiceApi) *retryingZoneService {
+	service := newRetryingZoneService(api)
+	service.baseDelay = time.Millisecond
+	return service
+}
+
+func TestRetryingZoneService_Throttled(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	service := newTestRetryingZoneService(api)
+	api.On("UpdateRecord", context.Background(), "123", "dummy.host", int64(42), dnsimple.ZoneRecordAttributes{}).Return(nil, throttledError(http.Header{})).Twice()
+	api.On("UpdateRecord", context.Background(), "123", "dummy.host", int64(42), dnsimple.ZoneRecordAttributes{}).Return(&dnsimple.ZoneRecordResponse{}, nil).Once()
+
+	_, err := service.UpdateRecord(context.Background(), "123", "dummy.host", 42, dnsimple.ZoneRecordAttributes{})
+
+	assert.NoError(t, err)
+	api.AssertNumberOfCalls(t, "UpdateRecord", 3)
+}
+
+func TestRetryingZoneService_AttemptsExhausted(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	service := newTestRetryingZoneService(api)
+	api.On("DeleteRecord", context.Background(), "123", "dummy.host", int64(42)).Return(nil, throttledError(http.Header{}))
+
+	_, err := service.DeleteRecord(context.Background(), "123", "dummy.host", 42)
+
+	_, throttled := throttledResponse(err)
+	assert.True(t, throttled, "Last throttling error returned")
+	api.AssertNumberOfCalls(t, "DeleteRecord", maxRetryAttempts)
+}
+
+func TestRetryingZoneService_OtherErrors(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	service := newTestRetryingZoneService(api)
+	failure := &dnsimple.ErrorResponse{Response: dnsimple.Response{HTTPResponse: &http.Response{StatusCode: http.StatusNotFound}}}
+	api.On("GetRecord", context.Background(), "123", "dummy.host", int64(42)).Return(nil, failure)
+
+	_, err := service.GetRecord(context.Background(), "123", "dummy.host", 42)
+
+	assert.True(t, errors.Is(err, failure))
+	api.AssertNumberOfCalls(t, "GetRecord", 1)
+}
+
+func TestRetryingZoneService_Canceled(t *testing.T) {
+	api := &mockDnsimpleZoneServiceInterface{}
+	service := newRetryingZoneService(api)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	api.On("ListRecords", ctx, "123", "dummy.host", (*dnsimple.ZoneRecordListOptions)(nil)).Return(nil, throttledError(http.Header{"Retry-After": []string{"60"}}))
+
+	_, err := service.ListRecords(ctx, "123", "dummy.host", nil)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	api.AssertNumberOfCalls(t, "ListRecords", 1)
+}
+
+func TestRetryingZoneService_RetryDelay(t *testing.T) {
+	service := newRetryingZoneService(nil)
+	now := time.Unix(1700000000, 0)
+	service.now = func() time.Time { return now }
+
+	assert.Equal(t, 4*time.Second, service.retryDelay(&http.Response{Header: http.Header{}}, 3), "Exponential backoff")
+	assert.Equal(t, 30*time.Second, service.retryDelay(&http.Response{Header: http.Header{"Retry-After": []string{"30"}}}, 1))
+	reset := strconv.FormatInt(now.Add(90*time.Second).Unix(), 10)
+	assert.Equal(t, 90*time.Second, service.retryDelay(&http.Response{Header: http.Header{"X-Ratelimit-Reset": []string{reset}}}, 1), "Waits for reset of rate limit window")
+	reset = strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	assert.Equal(t, maxRetryDelay, service.retryDelay(&http.Response{Header: http.Header{"X-Ratelimit-Reset": []string{reset}}}, 1), "Delay capped")
+}
@@ -0,0 +1,267 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/matic-insurance/dns-tager/pkg"
+	"github.com/matic-insurance/dns-tager/provider"
+	"github.com/matic-insurance/dns-tager/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// Attribute names used by External DNS DynamoDB registry
+const (
+	keyAttribute      = "k"
+	ownerAttribute    = "o"
+	labelsAttribute   = "l"
+	resourceLabel     = "resource"
+	ownerLabel        = "owner"
+	keySeparator      = "#"
+	keySeparatorParts = 3
+)
+
+// Retries of throttled requests, the SDK backs off exponentially up to maxRetryBackoff between attempts
+const (
+	maxRetryAttempts = 10
+	maxRetryBackoff  = time.Minute
+)
+
+type dynamodbRegistry struct {
+	cfg    *pkg.Config
+	client dynamodbApi
+	table  string
+}
+
+type dynamodbApi interface {
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// NewDynamoDBRegistry creates registry reading External DNS ownership from DynamoDB table.
+// Custom endpoint allows to use local DynamoDB compatible services
+func NewDynamoDBRegistry(ctx context.Context, cfg *pkg.Config) (provider.Registry, error) {
+	var options []func(*config.LoadOptions) error
+	if cfg.DynamoDBRegion != "" {
+		options = append(options, config.WithRegion(cfg.DynamoDBRegion))
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	client := dynamodb.NewFromConfig(awsConfig, func(o *dynamodb.Options) {
+		o.Retryer = newRetryer(maxRetryBackoff)
+		if cfg.DynamoDBEndpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.DynamoDBEndpoint)
+		}
+	})
+	return &dynamodbRegistry{cfg: cfg, client: client, table: cfg.DynamoDBTable}, nil
+}
+
+// newRetryer retries throttling errors and 429 responses besides errors retried by the SDK by default. Retries are
+// not limited by the SDK retry quota, so throttled migration slows down instead of failing once the quota is spent
+func newRetryer(maxBackoff time.Duration) aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = maxRetryAttempts
+		o.MaxBackoff = maxBackoff
+		o.Retryables = append(o.Retryables, retry.RetryableHTTPStatusCode{Codes: map[int]struct{}{http.StatusTooManyRequests: {}}})
+		o.RateLimiter = unlimitedRetries{}
+	})
+}
+
+// unlimitedRetries is retry quota that never runs out
+type unlimitedRetries struct{}
+
+func (unlimitedRetries) GetToken(context.Context, uint) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func (unlimitedRetries) AddTokens(uint) error {
+	return nil
+}
+
+func (r *dynamodbRegistry) Whoami(_ context.Context) string {
+	return fmt.Sprintf("DynamoDB registry in table %s", r.table)
+}
+
+func (r *dynamodbRegistry) ReadRecords(ctx context.Context) ([]*registry.Record, error) {
+	records := make([]*registry.Record, 0)
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName:            aws.String(r.table),
+		ProjectionExpression: aws.String("#k,#o,#l"),
+		ExpressionAttributeNames: map[string]string{
+			"#k": keyAttribute,
+			"#o": ownerAttribute,
+			"#l": labelsAttribute,
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			record, err := parseItem(item)
+			if err != nil {
+				log.Debugf("Skipping DynamoDB registry item: %s", err)
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (r *dynamodbRegistry) UpdateRegistryRecord(ctx context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	if !r.cfg.Apply {
+		log.Infof("Dry Run: Updated %s registry item to owner %s and resource %s", record.ID, record.Owner, record.Resource)
+		return 1, nil
+	}
+
+	_, err := r.client.UpdateItem(ctx, updateItemInput(r.table, record))
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// UpdateRegistryRecordIf updates the item only if it still has owner and resource of the expected record
+func (r *dynamodbRegistry) UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	if !r.cfg.Apply {
+		return r.UpdateRegistryRecord(ctx, zone, record)
+	}
+
+	input := updateItemInput(r.table, record)
+	input.ConditionExpression = aws.String("attribute_exists(#k) AND #o = :expectedOwner AND #l.#r = :expectedResource")
+	if expected.Resource == "" {
+		input.ConditionExpression = aws.String("attribute_exists(#k) AND #o = :expectedOwner AND (attribute_not_exists(#l.#r) OR #l.#r = :expectedResource)")
+	}
+	input.ExpressionAttributeNames["#r"] = resourceLabel
+	input.ExpressionAttributeValues[":expectedOwner"] = &types.AttributeValueMemberS{Value: expected.Owner}
+	input.ExpressionAttributeValues[":expectedResource"] = &types.AttributeValueMemberS{Value: expected.Resource}
+	_, err := r.client.UpdateItem(ctx, input)
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return 0, fmt.Errorf("%w: %s", provider.ErrRecordChanged, conditionFailed.ErrorMessage())
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// CreateRegistryRecord puts new item for the record, record without id gets key with empty set identifier
+func (r *dynamodbRegistry) CreateRegistryRecord(ctx context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	if record.ID == "" {
+		record.ID = strings.Join([]string{string(record.Name), record.RecordType, ""}, keySeparator)
+	}
+	if !r.cfg.Apply {
+		log.Infof("Dry Run: Created %s registry item with owner %s and resource %s", record.ID, record.Owner, record.Resource)
+		return 1, nil
+	}
+
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item: map[string]types.AttributeValue{
+			keyAttribute:    &types.AttributeValueMemberS{Value: record.ID},
+			ownerAttribute:  &types.AttributeValueMemberS{Value: record.Owner},
+			labelsAttribute: labelsValue(record),
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#k)"),
+		ExpressionAttributeNames: map[string]string{"#k": keyAttribute},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (r *dynamodbRegistry) DeleteRegistryRecord(ctx context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	if !r.cfg.Apply {
+		log.Infof("Dry Run: Deleted %s registry item", record.ID)
+		return 1, nil
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(r.table),
+		Key:                      map[string]types.AttributeValue{keyAttribute: &types.AttributeValueMemberS{Value: record.ID}},
+		ConditionExpression:      aws.String("attribute_exists(#k)"),
+		ExpressionAttributeNames: map[string]string{"#k": keyAttribute},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// updateItemInput sets owner and labels of the existing item
+func updateItemInput(table string, record *registry.Record) *dynamodb.UpdateItemInput {
+	return &dynamodb.UpdateItemInput{
+		TableName:           aws.String(table),
+		Key:                 map[string]types.AttributeValue{keyAttribute: &types.AttributeValueMemberS{Value: record.ID}},
+		UpdateExpression:    aws.String("SET #o = :owner, #l = :labels"),
+		ConditionExpression: aws.String("attribute_exists(#k)"),
+		ExpressionAttributeNames: map[string]string{
+			"#k": keyAttribute,
+			"#o": ownerAttribute,
+			"#l": labelsAttribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":  &types.AttributeValueMemberS{Value: record.Owner},
+			":labels": labelsValue(record),
+		},
+	}
+}
+
+// parseItem converts DynamoDB item with "name#type#set-identifier" key into registry record
+func parseItem(item map[string]types.AttributeValue) (*registry.Record, error) {
+	key, ok := item[keyAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("missing key attribute")
+	}
+	keyParts := strings.SplitN(key.Value, keySeparator, keySeparatorParts)
+	if len(keyParts) != keySeparatorParts {
+		return nil, fmt.Errorf("invalid key '%s'", key.Value)
+	}
+
+	record := &registry.Record{Name: registry.NewHostname(keyParts[0]), RecordType: keyParts[1], ID: key.Value}
+	if owner, ok := item[ownerAttribute].(*types.AttributeValueMemberS); ok {
+		record.Owner = owner.Value
+	}
+	if labels, ok := item[labelsAttribute].(*types.AttributeValueMemberM); ok {
+		for key, value := range labels.Value {
+			label, ok := value.(*types.AttributeValueMemberS)
+			switch {
+			case !ok || key == ownerLabel:
+				continue
+			case key == resourceLabel:
+				record.Resource = label.Value
+			default:
+				record.SetLabel(key, label.Value)
+			}
+		}
+	}
+	return record, nil
+}
+
+// labelsValue returns labels attribute with the record resource and extra labels
+func labelsValue(record *registry.Record) *types.AttributeValueMemberM {
+	labels := map[string]types.AttributeValue{resourceLabel: &types.AttributeValueMemberS{Value: record.Resource}}
+	for key, value := range record.Labels {
+		labels[key] = &types.AttributeValueMemberS{Value: value}
+	}
+	return &types.AttributeValueMemberM{Value: labels}
+}
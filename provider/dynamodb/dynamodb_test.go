@@ -0,0 +1,266 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/matic-insurance/dns-tager/pkg"
+	"github.com/matic-insurance/dns-tager/provider"
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDynamoDB is a local DynamoDB compatible stand-in supporting operations used by the registry
+type fakeDynamoDB struct {
+	items map[string]map[string]interface{}
+	// throttled responses are returned before requests are served
+	throttled []int
+	requests  int
+	url       string
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	f.requests++
+	if len(f.throttled) > 0 {
+		w.WriteHeader(f.throttled[0])
+		_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#ThrottlingException", "message": "Rate of requests exceeds the allowed throughput"})
+		f.throttled = f.throttled[1:]
+		return
+	}
+
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.") {
+	case "Scan":
+		items := make([]interface{}, 0)
+		for _, item := range f.items {
+			items = append(items, item)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Items": items, "Count": len(items), "ScannedCount": len(items)})
+	case "UpdateItem":
+		key := request["Key"].(map[string]interface{})[keyAttribute].(map[string]interface{})["S"].(string)
+		item, ok := f.items[key]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "The conditional request failed"})
+			return
+		}
+		values := request["ExpressionAttributeValues"].(map[string]interface{})
+		if expectedOwner, ok := values[":expectedOwner"]; ok && (fakeString(expectedOwner) != fakeString(item[ownerAttribute]) ||
+			fakeString(values[":expectedResource"]) != fakeString(fakeLabels(item)[resourceLabel])) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "The conditional request failed"})
+			return
+		}
+		item[ownerAttribute] = values[":owner"]
+		item[labelsAttribute] = values[":labels"]
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{})
+	case "PutItem":
+		item := request["Item"].(map[string]interface{})
+		key := item[keyAttribute].(map[string]interface{})["S"].(string)
+		if _, ok := f.items[key]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "The conditional request failed"})
+			return
+		}
+		f.items[key] = item
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{})
+	case "DeleteItem":
+		key := request["Key"].(map[string]interface{})[keyAttribute].(map[string]interface{})["S"].(string)
+		if _, ok := f.items[key]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "The conditional request failed"})
+			return
+		}
+		delete(f.items, key)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// fakeString returns value of the string attribute
+func fakeString(attribute interface{}) string {
+	data, _ := json.Marshal(attribute)
+	var value struct{ S string }
+	_ = json.Unmarshal(data, &value)
+	return value.S
+}
+
+// fakeLabels returns attributes of the item labels map
+func fakeLabels(item map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(item[labelsAttribute])
+	var labels struct{ M map[string]interface{} }
+	_ = json.Unmarshal(data, &labels)
+	return labels.M
+}
+
+func newFakeItem(key string, owner string, resource string) map[string]interface{} {
+	return map[string]interface{}{
+		keyAttribute:    map[string]string{"S": key},
+		ownerAttribute:  map[string]string{"S": owner},
+		labelsAttribute: map[string]interface{}{"M": map[string]interface{}{resourceLabel: map[string]string{"S": resource}}},
+	}
+}
+
+func assertFakeItem(t *testing.T, want map[string]interface{}, got map[string]interface{}) {
+	t.Helper()
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	assert.JSONEq(t, string(wantJSON), string(gotJSON))
+}
+
+func newTestRegistry(t *testing.T, apply bool) (*dynamodbRegistry, *fakeDynamoDB) {
+	fake := &fakeDynamoDB{items: map[string]map[string]interface{}{
+		"webserver.dummy.host#A#": newFakeItem("webserver.dummy.host#A#", "cluster-1", "ingress/test/webserver"),
+		"invalid-key":             newFakeItem("invalid-key", "cluster-1", "ingress/test/webserver"),
+	}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		RetryMaxAttempts: 1,
+	})
+	return &dynamodbRegistry{cfg: &pkg.Config{Apply: apply}, client: client, table: "external-dns"}, fake
+}
+
+func TestDynamoDBRegistry_ReadRecords(t *testing.T) {
+	testRegistry, _ := newTestRegistry(t, true)
+
+	records, err := testRegistry.ReadRecords(context.Background())
+
+	assert.NoError(t, err)
+	want := []*registry.Record{{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#", Owner: "cluster-1", Resource: "ingress/test/webserver"}}
+	assert.Equal(t, want, records, "Should parse valid items only")
+}
+
+func TestDynamoDBRegistry_UpdateRegistryRecord(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#", Owner: "cluster-2", Resource: "virtualservice/test/webserver"}
+
+	updates, err := testRegistry.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Correct updates count returned")
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-2", "virtualservice/test/webserver"), fake.items[record.ID])
+}
+
+func TestDynamoDBRegistry_UpdateRegistryRecord_Throttled(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	testRegistry.client = dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(fake.url),
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+		Retryer:      newRetryer(time.Millisecond),
+	})
+	fake.throttled = []int{http.StatusTooManyRequests, http.StatusBadRequest}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#", Owner: "cluster-2", Resource: "virtualservice/test/webserver"}
+
+	updates, err := testRegistry.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Throttled request retried")
+	assert.Equal(t, 3, fake.requests)
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-2", "virtualservice/test/webserver"), fake.items[record.ID])
+}
+
+func TestDynamoDBRegistry_UpdateRegistryRecord_Missing(t *testing.T) {
+	testRegistry, _ := newTestRegistry(t, true)
+	record := &registry.Record{Name: "missing.dummy.host", RecordType: "A", ID: "missing.dummy.host#A#", Owner: "cluster-2"}
+
+	updates, err := testRegistry.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, updates, "Zero updates count returned")
+}
+
+func TestDynamoDBRegistry_UpdateRegistryRecordIf(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	expected := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#", Owner: "cluster-0", Resource: "ingress/test/webserver"}
+	record := expected.NewRecord("cluster-2", "ingress/test/webserver")
+
+	updates, err := testRegistry.UpdateRegistryRecordIf(context.Background(), registry.NewZone("dummy.host"), expected, record)
+	assert.ErrorIs(t, err, provider.ErrRecordChanged)
+	assert.Equal(t, 0, updates, "Item owned by another owner is not updated")
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-1", "ingress/test/webserver"), fake.items[record.ID])
+
+	expected.Owner = "cluster-1"
+	updates, err = testRegistry.UpdateRegistryRecordIf(context.Background(), registry.NewZone("dummy.host"), expected, record)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Item with expected owner and resource updated")
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-2", "ingress/test/webserver"), fake.items[record.ID])
+}
+
+func TestDynamoDBRegistry_UpdateRegistryRecord_NoApply(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, false)
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#", Owner: "cluster-2"}
+
+	updates, err := testRegistry.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates, "Correct updates count returned")
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-1", "ingress/test/webserver"), fake.items[record.ID])
+}
+
+func TestDynamoDBRegistry_DeleteRegistryRecord(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", ID: "webserver.dummy.host#A#"}
+
+	deletes, err := testRegistry.DeleteRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletes, "Correct deletes count returned")
+	assert.NotContains(t, fake.items, record.ID)
+
+	deletes, err = testRegistry.DeleteRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+	assert.Error(t, err, "Missing item is not deleted")
+	assert.Equal(t, 0, deletes, "Zero deletes count returned")
+}
+
+func TestDynamoDBRegistry_CreateRegistryRecord(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	record := &registry.Record{Name: "api.dummy.host", RecordType: "CNAME", Owner: "cluster-2", Resource: "ingress/test/api"}
+
+	creates, err := testRegistry.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, creates, "Correct creates count returned")
+	assert.Equal(t, "api.dummy.host#CNAME#", record.ID)
+	assertFakeItem(t, newFakeItem(record.ID, "cluster-2", "ingress/test/api"), fake.items[record.ID])
+
+	creates, err = testRegistry.CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+	assert.Error(t, err, "Existing item is not overwritten")
+	assert.Equal(t, 0, creates, "Zero creates count returned")
+}
+
+func TestDynamoDBRegistry_Labels(t *testing.T) {
+	testRegistry, fake := newTestRegistry(t, true)
+	key := "webserver.dummy.host#A#"
+	fake.items[key][labelsAttribute].(map[string]interface{})["M"].(map[string]interface{})["frozen-owner"] = map[string]string{"S": "cluster-1"}
+
+	records, err := testRegistry.ReadRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"frozen-owner": "cluster-1"}, records[0].Labels, "Extra labels read")
+
+	updated := records[0].NewRecord("cluster-2", records[0].Resource)
+	updated.SetLabel("note", "parked")
+	_, err = testRegistry.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), updated)
+	assert.NoError(t, err)
+	want := newFakeItem(key, "cluster-2", "ingress/test/webserver")
+	want[labelsAttribute].(map[string]interface{})["M"].(map[string]interface{})["frozen-owner"] = map[string]string{"S": "cluster-1"}
+	want[labelsAttribute].(map[string]interface{})["M"].(map[string]interface{})["note"] = map[string]string{"S": "parked"}
+	assertFakeItem(t, want, fake.items[key])
+}
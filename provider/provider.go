@@ -7,8 +7,10 @@ import (
 
 type Provider interface {
 	Whoami(ctx context.Context) string
-	ReadZones(ctx context.Context) ([]*registry.Zone, error)
+	ReadZones(ctx context.Context, matcher *registry.Matcher) ([]*registry.Zone, error)
 	UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (updatedRecords int, err error)
+	CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (createdRecords int, err error)
+	DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (deletedRecords int, err error)
 }
 
 type BaseProvider struct{}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"golang.org/x/time/rate"
+)
+
+type rateLimitedProvider struct {
+	Provider
+	limiter *rate.Limiter
+}
+
+// WithRateLimit limits registry record changes to requestsPerSecond shared by all callers.
+// Reading zones is not limited. Zero or negative limit returns provider unchanged
+func WithRateLimit(p Provider, requestsPerSecond float64) Provider {
+	if requestsPerSecond <= 0 {
+		return p
+	}
+	return &rateLimitedProvider{Provider: p, limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), 1)}
+}
+
+func (p *rateLimitedProvider) Whoami(ctx context.Context) string {
+	return fmt.Sprintf("%s limited to %g changes per second", p.Provider.Whoami(ctx), float64(p.limiter.Limit()))
+}
+
+func (p *rateLimitedProvider) UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return p.Provider.UpdateRegistryRecord(ctx, zone, record)
+}
+
+func (p *rateLimitedProvider) UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return UpdateRegistryRecordIf(ctx, p.Provider, zone, expected, record)
+}
+
+func (p *rateLimitedProvider) CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return p.Provider.CreateRegistryRecord(ctx, zone, record)
+}
+
+func (p *rateLimitedProvider) DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return p.Provider.DeleteRegistryRecord(ctx, zone, record)
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRateLimit_Unlimited(t *testing.T) {
+	ownership := &staticRegistry{}
+	p := WithRegistry(&staticProvider{}, ownership)
+
+	assert.Same(t, p, WithRateLimit(p, 0), "Provider is not wrapped without limit")
+}
+
+func TestWithRateLimit_UpdateRegistryRecord(t *testing.T) {
+	ownership := &staticRegistry{}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-2"}
+	limited := WithRateLimit(WithRegistry(&staticProvider{}, ownership), 20)
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		updates, err := limited.UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updates)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(started), 90*time.Millisecond, "Changes are spaced by the limit")
+	assert.Len(t, ownership.updated, 3)
+}
+
+func TestWithRateLimit_Canceled(t *testing.T) {
+	ownership := &staticRegistry{}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-2"}
+	limited := WithRateLimit(WithRegistry(&staticProvider{}, ownership), 0.001)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := limited.CreateRegistryRecord(ctx, registry.NewZone("dummy.host"), record)
+	assert.NoError(t, err, "First change uses the burst")
+	cancel()
+	_, err = limited.CreateRegistryRecord(ctx, registry.NewZone("dummy.host"), record)
+	assert.Error(t, err)
+	assert.Len(t, ownership.created, 1)
+}
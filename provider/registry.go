@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/matic-insurance/dns-tager/registry"
+)
+
+// Registry stores External DNS ownership information outside of the DNS provider
+type Registry interface {
+	Whoami(ctx context.Context) string
+	ReadRecords(ctx context.Context) ([]*registry.Record, error)
+	UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (updatedRecords int, err error)
+	CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (createdRecords int, err error)
+	DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (deletedRecords int, err error)
+}
+
+type registryProvider struct {
+	Provider
+	ownership Registry
+}
+
+// WithRegistry combines hosts read from the DNS provider with ownership information stored in the registry.
+// Registry TXT records from the DNS provider are ignored
+func WithRegistry(hosts Provider, ownership Registry) Provider {
+	return &registryProvider{Provider: hosts, ownership: ownership}
+}
+
+func (p *registryProvider) Whoami(ctx context.Context) string {
+	return fmt.Sprintf("%s with %s", p.Provider.Whoami(ctx), p.ownership.Whoami(ctx))
+}
+
+func (p *registryProvider) ReadZones(ctx context.Context, matcher *registry.Matcher) ([]*registry.Zone, error) {
+	zones, err := p.Provider.ReadZones(ctx, matcher)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.ownership.ReadRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zone := range zones {
+		zone.RegistryRecords = make([]*registry.Record, 0)
+	}
+	recordsByHost := make(map[string][]*registry.Record)
+	for _, record := range records {
+		key := string(record.Name) + "#" + record.RecordType
+		recordsByHost[key] = append(recordsByHost[key], record)
+		if zone := registry.FindZone(zones, record.Name); zone != nil {
+			zone.AddRegistryRecord(record)
+		}
+	}
+	for _, zone := range zones {
+		for _, host := range zone.Hosts {
+			host.RegistryRecords = make([]*registry.Record, 0)
+			for _, record := range recordsByHost[string(host.Name)+"#"+host.RecordType] {
+				host.AddRegistryRecord(record)
+			}
+		}
+	}
+	return zones, nil
+}
+
+func (p *registryProvider) UpdateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	return p.ownership.UpdateRegistryRecord(ctx, zone, record)
+}
+
+// UpdateRegistryRecordIf uses conditional update of the registry when it is supported
+func (p *registryProvider) UpdateRegistryRecordIf(ctx context.Context, zone *registry.Zone, expected *registry.Record, record *registry.Record) (int, error) {
+	if updater, ok := p.ownership.(ConditionalUpdater); ok {
+		return updater.UpdateRegistryRecordIf(ctx, zone, expected, record)
+	}
+	return p.ownership.UpdateRegistryRecord(ctx, zone, record)
+}
+
+func (p *registryProvider) CreateRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	return p.ownership.CreateRegistryRecord(ctx, zone, record)
+}
+
+func (p *registryProvider) DeleteRegistryRecord(ctx context.Context, zone *registry.Zone, record *registry.Record) (int, error) {
+	return p.ownership.DeleteRegistryRecord(ctx, zone, record)
+}
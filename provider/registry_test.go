@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matic-insurance/dns-tager/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticProvider struct {
+	BaseProvider
+	zones []*registry.Zone
+}
+
+func (p *staticProvider) Whoami(_ context.Context) string {
+	return "Static"
+}
+
+func (p *staticProvider) ReadZones(_ context.Context, _ *registry.Matcher) ([]*registry.Zone, error) {
+	return p.zones, nil
+}
+
+func (p *staticProvider) UpdateRegistryRecord(_ context.Context, _ *registry.Zone, _ *registry.Record) (int, error) {
+	panic("registry records should be updated in registry")
+}
+
+func (p *staticProvider) CreateRegistryRecord(_ context.Context, _ *registry.Zone, _ *registry.Record) (int, error) {
+	panic("registry records should be created in registry")
+}
+
+func (p *staticProvider) DeleteRegistryRecord(_ context.Context, _ *registry.Zone, _ *registry.Record) (int, error) {
+	panic("registry records should be deleted in registry")
+}
+
+type staticRegistry struct {
+	records []*registry.Record
+	updated []*registry.Record
+	created []*registry.Record
+	deleted []*registry.Record
+}
+
+func (r *staticRegistry) Whoami(_ context.Context) string {
+	return "Static Registry"
+}
+
+func (r *staticRegistry) ReadRecords(_ context.Context) ([]*registry.Record, error) {
+	return r.records, nil
+}
+
+func (r *staticRegistry) UpdateRegistryRecord(_ context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	r.updated = append(r.updated, record)
+	return 1, nil
+}
+
+func (r *staticRegistry) CreateRegistryRecord(_ context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	r.created = append(r.created, record)
+	return 1, nil
+}
+
+func (r *staticRegistry) DeleteRegistryRecord(_ context.Context, _ *registry.Zone, record *registry.Record) (int, error) {
+	r.deleted = append(r.deleted, record)
+	return 1, nil
+}
+
+func TestWithRegistry_ReadZones(t *testing.T) {
+	zone := registry.NewZone("dummy.host")
+	aHost := registry.NewHost("webserver.dummy.host", "A", "127.0.0.1")
+	aHost.AddRegistryRecord(registry.NewRecord("edns-webserver.dummy.host", "heritage=external-dns"))
+	cnameHost := registry.NewHost("api.dummy.host", "CNAME", "webserver.dummy.host")
+	zone.AddHost(aHost)
+	zone.AddHost(cnameHost)
+	aRecord := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-1"}
+	ownership := &staticRegistry{records: []*registry.Record{
+		aRecord,
+		{Name: "webserver.dummy.host", RecordType: "AAAA", Owner: "cluster-1"},
+		{Name: "deleted.dummy.host", RecordType: "A", Owner: "cluster-1"},
+	}}
+
+	zones, err := WithRegistry(&staticProvider{zones: []*registry.Zone{zone}}, ownership).ReadZones(context.Background(), registry.NewMatcher("", "", ""))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.Record{aRecord}, zones[0].Hosts[0].RegistryRecords, "Registry records replaced by matching name and type")
+	assert.False(t, zones[0].Hosts[1].IsManaged(), "Host without registry item is unmanaged")
+	assert.Equal(t, []*registry.Record{ownership.records[2]}, zones[0].OrphanRecords(registry.NewMatcher("", "", "")), "Registry items of names without DNS records are orphans")
+}
+
+func TestWithRegistry_UpdateRegistryRecord(t *testing.T) {
+	ownership := &staticRegistry{}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-2"}
+
+	updates, err := WithRegistry(&staticProvider{}, ownership).UpdateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, []*registry.Record{record}, ownership.updated)
+}
+
+func TestWithRegistry_UpdateRegistryRecordIf(t *testing.T) {
+	ownership := &staticRegistry{}
+	expected := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-1"}
+	record := expected.NewRecord("cluster-2", "")
+
+	updates, err := UpdateRegistryRecordIf(context.Background(), WithRegistry(&staticProvider{}, ownership), registry.NewZone("dummy.host"), expected, record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updates)
+	assert.Equal(t, []*registry.Record{record}, ownership.updated, "Registry without conditional updates updates record unconditionally")
+}
+
+func TestWithRegistry_DeleteRegistryRecord(t *testing.T) {
+	ownership := &staticRegistry{}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-2"}
+
+	deletes, err := WithRegistry(&staticProvider{}, ownership).DeleteRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletes)
+	assert.Equal(t, []*registry.Record{record}, ownership.deleted)
+}
+
+func TestWithRegistry_CreateRegistryRecord(t *testing.T) {
+	ownership := &staticRegistry{}
+	record := &registry.Record{Name: "webserver.dummy.host", RecordType: "A", Owner: "cluster-2"}
+
+	creates, err := WithRegistry(&staticProvider{}, ownership).CreateRegistryRecord(context.Background(), registry.NewZone("dummy.host"), record)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, creates)
+	assert.Equal(t, []*registry.Record{record}, ownership.created)
+}
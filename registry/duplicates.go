@@ -0,0 +1,67 @@
+package registry
+
+// RecordConflict describes registry records of the host that disagree with each other or duplicate each other
+type RecordConflict struct {
+	Host *Host
+	// Canonical record External DNS relies on: the first new format record of the host type, or the first record
+	Canonical *Record
+	// OwnerMismatch is set when registry records have different owners
+	OwnerMismatch bool
+	// ResourceMismatch is set when registry records have different resources
+	ResourceMismatch bool
+	// MixedFormats is set when host has both legacy and new format registry records
+	MixedFormats bool
+	// Duplicates are redundant records with the same name as another registry record of the host
+	Duplicates []*Record
+}
+
+// AnalyzeRegistryRecords checks registry records of the host, returns nil when host has a single registry record
+func AnalyzeRegistryRecords(host *Host) *RecordConflict {
+	if len(host.RegistryRecords) < 2 {
+		return nil
+	}
+
+	conflict := &RecordConflict{Host: host, Canonical: host.RegistryRecords[0], Duplicates: make([]*Record, 0)}
+	for _, record := range host.RegistryRecords {
+		if host.RecordType != "" && record.RecordType == host.RecordType {
+			conflict.Canonical = record
+			break
+		}
+	}
+
+	legacy, recordType := false, false
+	seen := map[Hostname]bool{conflict.Canonical.Name: true}
+	for _, record := range host.RegistryRecords {
+		if record.RecordType == "" {
+			legacy = true
+		} else {
+			recordType = true
+		}
+		conflict.OwnerMismatch = conflict.OwnerMismatch || record.Owner != conflict.Canonical.Owner
+		conflict.ResourceMismatch = conflict.ResourceMismatch || record.Resource != conflict.Canonical.Resource
+		if record == conflict.Canonical {
+			continue
+		}
+		if seen[record.Name] {
+			conflict.Duplicates = append(conflict.Duplicates, record)
+		}
+		seen[record.Name] = true
+	}
+	conflict.MixedFormats = legacy && recordType
+	return conflict
+}
+
+// HasConflict returns true when registry records disagree or some of them are redundant
+func (c *RecordConflict) HasConflict() bool {
+	return c.OwnerMismatch || c.ResourceMismatch || c.MixedFormats || len(c.Duplicates) > 0
+}
+
+// IsDuplicate checks whether record is one of the redundant duplicates
+func (c *RecordConflict) IsDuplicate(record *Record) bool {
+	for _, duplicate := range c.Duplicates {
+		if duplicate == record {
+			return true
+		}
+	}
+	return false
+}
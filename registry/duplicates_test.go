@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnalyzeRegistryRecords_SingleRecord(t *testing.T) {
+	host := NewHost("test.example.com", "A", "127.0.0.1")
+	host.AddRegistryRecord(&Record{Name: "edns-test.example.com", Owner: "cluster-1"})
+	assert.Nil(t, AnalyzeRegistryRecords(host))
+}
+
+func TestAnalyzeRegistryRecords(t *testing.T) {
+	legacy := &Record{Name: "edns-test.example.com", Owner: "cluster-1", Resource: "ingress/test/web"}
+	typed := &Record{Name: "edns-a-test.example.com", RecordType: "A", Owner: "cluster-1", Resource: "ingress/test/web"}
+	typedStray := &Record{Name: "edns-a-test.example.com", RecordType: "A", Owner: "cluster-2", Resource: "ingress/test/web"}
+	legacyOther := &Record{Name: "edns-test.example.com", Owner: "cluster-1", Resource: "ingress/test/api"}
+
+	tests := []struct {
+		name       string
+		records    []*Record
+		canonical  *Record
+		owner      bool
+		resource   bool
+		mixed      bool
+		duplicates []*Record
+		conflict   bool
+	}{
+		{name: "Legacy and new format", records: []*Record{legacy, typed}, canonical: typed, mixed: true, duplicates: []*Record{}, conflict: true},
+		{name: "Stray duplicate with other owner", records: []*Record{typedStray, typed}, canonical: typedStray, owner: true, duplicates: []*Record{typed}, conflict: true},
+		{name: "Legacy duplicates with other resource", records: []*Record{legacy, legacyOther}, canonical: legacy, resource: true, duplicates: []*Record{legacyOther}, conflict: true},
+		{name: "Identical new format records", records: []*Record{typed, typed}, canonical: typed, duplicates: []*Record{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := NewHost("test.example.com", "A", "127.0.0.1")
+			for _, record := range tt.records {
+				host.AddRegistryRecord(record)
+			}
+
+			conflict := AnalyzeRegistryRecords(host)
+			assert.Same(t, tt.canonical, conflict.Canonical)
+			assert.Equal(t, tt.owner, conflict.OwnerMismatch)
+			assert.Equal(t, tt.resource, conflict.ResourceMismatch)
+			assert.Equal(t, tt.mixed, conflict.MixedFormats)
+			assert.Equal(t, tt.duplicates, conflict.Duplicates)
+			assert.Equal(t, tt.conflict, conflict.HasConflict())
+		})
+	}
+}
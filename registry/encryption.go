@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const encryptionKeySize = 32
+
+// Encryptor encrypts and decrypts registry records content with AES-GCM the same way External DNS does
+// when started with --txt-encrypt-enabled
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor creates encryptor from 32 bytes AES key in plain text or base64 encoded format
+func NewEncryptor(key string) (*Encryptor, error) {
+	keyBytes := []byte(key)
+	if len(keyBytes) != encryptionKeySize {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != encryptionKeySize {
+			return nil, fmt.Errorf("the AES encryption key must be 32 bytes long, in either plain text or base64-encoded format")
+		}
+		keyBytes = decoded
+	}
+
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Encryptor{aead: aead}, nil
+}
+
+// Decrypt returns plain text of encrypted content and whether it was gzip compressed before encryption
+func (e *Encryptor) Decrypt(content string) (text string, compressed bool, err error) {
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", false, err
+	}
+	nonceSize := e.aead.NonceSize()
+	if len(data) <= nonceSize {
+		return "", false, fmt.Errorf("encrypted content is shorter than %d bytes", nonceSize)
+	}
+
+	plain, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", false, err
+	}
+	if !isGzip(plain) {
+		return string(plain), false, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return "", false, err
+	}
+	defer reader.Close()
+	plain, err = io.ReadAll(reader)
+	if err != nil {
+		return "", false, err
+	}
+	return string(plain), true, nil
+}
+
+// Encrypt encrypts plain text with a fresh random nonce, optionally gzip compressing it first
+func (e *Encryptor) Encrypt(text string, compress bool) (string, error) {
+	data := []byte(text)
+	if compress {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		if _, err := writer.Write(data); err != nil {
+			return "", err
+		}
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+		data = buffer.Bytes()
+	}
+
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(e.aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewEncryptor_KeyFormats(t *testing.T) {
+	_, err := NewEncryptor(testEncryptionKey)
+	assert.NoError(t, err, "Plain text key accepted")
+
+	_, err = NewEncryptor(base64.StdEncoding.EncodeToString([]byte(testEncryptionKey)))
+	assert.NoError(t, err, "Base64 key accepted")
+
+	_, err = NewEncryptor("short-key")
+	assert.Error(t, err, "Short key rejected")
+}
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	encryptor, _ := NewEncryptor(testEncryptionKey)
+	info := "heritage=external-dns,external-dns/owner=matic,external-dns/resource=ingress/test/webserver"
+
+	for _, compress := range []bool{false, true} {
+		encrypted, err := encryptor.Encrypt(info, compress)
+		assert.NoError(t, err)
+		assert.NotContains(t, encrypted, ExternalDnsIdentifier)
+
+		decrypted, compressed, err := encryptor.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, info, decrypted)
+		assert.Equal(t, compress, compressed)
+	}
+}
+
+func TestEncryptor_FreshNonce(t *testing.T) {
+	encryptor, _ := NewEncryptor(testEncryptionKey)
+	first, _ := encryptor.Encrypt("heritage=external-dns", false)
+	second, _ := encryptor.Encrypt("heritage=external-dns", false)
+	assert.NotEqual(t, first, second, "Every encryption uses new nonce")
+}
+
+func TestEncryptor_DecryptWrongKey(t *testing.T) {
+	encryptor, _ := NewEncryptor(testEncryptionKey)
+	anotherEncryptor, _ := NewEncryptor("abcdef0123456789abcdef0123456789")
+	encrypted, _ := encryptor.Encrypt("heritage=external-dns", false)
+
+	_, _, err := anotherEncryptor.Decrypt(encrypted)
+	assert.Error(t, err)
+	_, _, err = encryptor.Decrypt("c2hvcnQ=")
+	assert.Error(t, err)
+}
@@ -3,12 +3,14 @@ package registry
 import "fmt"
 
 type Endpoint struct {
-	Host     string
+	Host     Hostname
 	Resource string
+	// Source name the endpoint was collected from, e.g. ingress or istio-virtualservice
+	Source string
 }
 
-func NewEndpoint(host string, resource string) *Endpoint {
-	return &Endpoint{Resource: resource, Host: host}
+func NewEndpoint(host string, resource string, source string) *Endpoint {
+	return &Endpoint{Resource: resource, Host: NewHostname(host), Source: source}
 }
 
 func (e Endpoint) String() string {
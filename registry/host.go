@@ -2,21 +2,26 @@ package registry
 
 import "fmt"
 
+// Host is a DNS record set - all values of the same name and record type
 type Host struct {
-	Name            string
+	Name            Hostname
 	RecordType      string
-	Value           string
+	Values          []string
 	RegistryRecords []*Record
 }
 
 func (h *Host) String() string {
 	if h.IsManaged() {
-		return fmt.Sprintf("Host[Managed][Dns:%s]", h.Name)
+		return fmt.Sprintf("Host[Managed][Dns:%s][Type:%s]", h.Name, h.RecordType)
 	} else {
-		return fmt.Sprintf("Host[Unmanaged][Dns:%s]", h.Name)
+		return fmt.Sprintf("Host[Unmanaged][Dns:%s][Type:%s]", h.Name, h.RecordType)
 	}
 }
 
+func (h *Host) AddValue(value string) {
+	h.Values = append(h.Values, value)
+}
+
 func (h *Host) AddRegistryRecord(record *Record) {
 	h.RegistryRecords = append(h.RegistryRecords, record)
 }
@@ -25,6 +30,6 @@ func (h *Host) IsManaged() bool {
 	return len(h.RegistryRecords) > 0
 }
 
-func NewHost(name string, recordType string, value string) *Host {
-	return &Host{Name: name, RecordType: recordType, Value: value, RegistryRecords: make([]*Record, 0)}
+func NewHost(name string, recordType string, values ...string) *Host {
+	return &Host{Name: NewHostname(name), RecordType: recordType, Values: values, RegistryRecords: make([]*Record, 0)}
 }
@@ -15,3 +15,9 @@ func TestHost_IsManaged_NoRegistryRecords(t *testing.T) {
 	host := NewHost("test.example.com", "CNAME", "test")
 	assert.Equal(t, false, host.IsManaged())
 }
+
+func TestHost_AddValue(t *testing.T) {
+	host := NewHost("test.example.com", "A", "127.0.0.1")
+	host.AddValue("127.0.0.2")
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2"}, host.Values)
+}
@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Hostname is a canonical DNS name: lower case, without trailing dot and with IDN converted to A-labels
+type Hostname string
+
+var idnaProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
+func NewHostname(name string) Hostname {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if !isASCII(name) {
+		if ascii, err := idnaProfile.ToASCII(name); err == nil {
+			name = ascii
+		}
+	}
+	return Hostname(strings.ToLower(name))
+}
+
+func isASCII(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewHostname(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want Hostname
+	}{
+		{name: "Canonical", host: "web.example.com", want: "web.example.com"},
+		{name: "Upper case", host: "Web.Example.COM", want: "web.example.com"},
+		{name: "Trailing dot", host: "web.example.com.", want: "web.example.com"},
+		{name: "Unicode", host: "bücher.example.com", want: "xn--bcher-kva.example.com"},
+		{name: "Unicode upper case with trailing dot", host: "Bücher.Example.com.", want: "xn--bcher-kva.example.com"},
+		{name: "Punycode", host: "xn--bcher-kva.example.com", want: "xn--bcher-kva.example.com"},
+		{name: "Wildcard", host: "*.Example.com", want: "*.example.com"},
+		{name: "Registry prefix", host: "_extdns.Web.example.com", want: "_extdns.web.example.com"},
+		{name: "Single label", host: "Localhost", want: "localhost"},
+		{name: "Empty", host: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewHostname(tt.host))
+		})
+	}
+}
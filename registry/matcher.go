@@ -0,0 +1,177 @@
+package registry
+
+import "strings"
+
+// RecordTypeTemplate is replaced with the host record type when used in TXT prefix or suffix
+const RecordTypeTemplate = "%{record_type}"
+
+const wildcardLabel = "*"
+
+// DefaultRecordTypes are host record types managed by External DNS by default
+var DefaultRecordTypes = []string{"A", "AAAA", "CNAME"}
+
+// KnownRecordTypes are record types External DNS can create registry records for, used to recognize new format
+// registry record names of hosts with types that are not managed
+var KnownRecordTypes = []string{"A", "AAAA", "CNAME", "NS", "MX", "SRV", "CAA", "ALIAS", "TXT", "PTR", "NAPTR"}
+
+// Matcher resolves registry record names the same way External DNS generates them
+// from configured TXT prefix, suffix and wildcard replacement
+type Matcher struct {
+	Prefix              string
+	Suffix              string
+	WildcardReplacement string
+	// RecordTypes of the hosts managed by External DNS, may include provider specific types like ALIAS
+	RecordTypes []string
+}
+
+func NewMatcher(prefix string, suffix string, wildcardReplacement string) *Matcher {
+	return &Matcher{Prefix: prefix, Suffix: suffix, WildcardReplacement: wildcardReplacement, RecordTypes: DefaultRecordTypes}
+}
+
+// IsHostRecordType checks whether DNS records of the type are managed hosts
+func (m *Matcher) IsHostRecordType(recordType string) bool {
+	for _, managedType := range m.RecordTypes {
+		if strings.EqualFold(managedType, recordType) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryNames returns every registry record name External DNS can create for the host in the zone:
+// legacy names without record type and new format names with record type
+func (m *Matcher) RegistryNames(zone *Zone, host *Host) []Hostname {
+	return append(m.LegacyNames(zone, host), m.RecordTypeNames(zone, host)...)
+}
+
+// LegacyNames returns registry record names without record type, shared by all record sets of the host
+func (m *Matcher) LegacyNames(zone *Zone, host *Host) []Hostname {
+	prefix, suffix := m.legacyAffixes()
+	return m.affixedNames(zone, host.Name, prefix, suffix)
+}
+
+// RecordTypeNames returns new format registry record names dedicated to the record set type of the host
+func (m *Matcher) RecordTypeNames(zone *Zone, host *Host) []Hostname {
+	if host.RecordType == "" {
+		return nil
+	}
+	prefix, suffix := m.recordTypeAffixes(host.RecordType)
+	return m.affixedNames(zone, host.Name, prefix, suffix)
+}
+
+// HostNames returns names of the hosts the registry record name can be generated for: by legacy affixes
+// or by affixes of any known record type, regardless of managed record types
+func (m *Matcher) HostNames(zone *Zone, name Hostname) []Hostname {
+	var hosts []Hostname
+	prefix, suffix := m.legacyAffixes()
+	if host, ok := m.stripAffixes(zone, name, prefix, suffix); ok {
+		hosts = append(hosts, host)
+	}
+	for _, recordType := range m.knownRecordTypes() {
+		prefix, suffix := m.recordTypeAffixes(recordType)
+		if host, ok := m.stripAffixes(zone, name, prefix, suffix); ok {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// RecordType returns record type of the new format registry record name, empty for legacy names
+func (m *Matcher) RecordType(zone *Zone, name Hostname) string {
+	for _, recordType := range m.knownRecordTypes() {
+		prefix, suffix := m.recordTypeAffixes(recordType)
+		if _, ok := m.stripAffixes(zone, name, prefix, suffix); ok {
+			return recordType
+		}
+	}
+	return ""
+}
+
+// knownRecordTypes returns managed record types followed by the rest of known record types
+func (m *Matcher) knownRecordTypes() []string {
+	recordTypes := make([]string, 0, len(m.RecordTypes)+len(KnownRecordTypes))
+	seen := make(map[string]bool)
+	for _, recordType := range append(append([]string{}, m.RecordTypes...), KnownRecordTypes...) {
+		recordType = strings.ToUpper(recordType)
+		if !seen[recordType] {
+			seen[recordType] = true
+			recordTypes = append(recordTypes, recordType)
+		}
+	}
+	return recordTypes
+}
+
+func (m *Matcher) legacyAffixes() (string, string) {
+	return strings.ReplaceAll(m.Prefix, RecordTypeTemplate, ""), strings.ReplaceAll(m.Suffix, RecordTypeTemplate, "")
+}
+
+func (m *Matcher) recordTypeAffixes(recordType string) (string, string) {
+	recordType = strings.ToLower(recordType)
+	prefix := strings.ReplaceAll(m.Prefix, RecordTypeTemplate, recordType)
+	suffix := strings.ReplaceAll(m.Suffix, RecordTypeTemplate, recordType)
+	if !m.hasRecordTypeTemplate() {
+		prefix = prefix + recordType + "-"
+	}
+	return prefix, suffix
+}
+
+func (m *Matcher) hasRecordTypeTemplate() bool {
+	return strings.Contains(m.Prefix, RecordTypeTemplate) || strings.Contains(m.Suffix, RecordTypeTemplate)
+}
+
+// affixedNames returns registry names for the host name. Apex hosts can have registry record either
+// affixed to the zone name (<prefix>example.com) or as affix label inside the zone (<prefix>.example.com)
+func (m *Matcher) affixedNames(zone *Zone, name Hostname, prefix string, suffix string) []Hostname {
+	names := []Hostname{NewHostname(affixName(m.replaceWildcard(string(name)), prefix, suffix))}
+	if zone != nil && name == zone.Name {
+		if label := strings.Trim(prefix+suffix, "."); label != "" {
+			if apexLabelName := NewHostname(label + "." + string(zone.Name)); apexLabelName != names[0] {
+				names = append(names, apexLabelName)
+			}
+		}
+	}
+	return names
+}
+
+func (m *Matcher) replaceWildcard(name string) string {
+	if m.WildcardReplacement != "" && strings.HasPrefix(name, wildcardLabel+".") {
+		return m.WildcardReplacement + strings.TrimPrefix(name, wildcardLabel)
+	}
+	return name
+}
+
+// stripAffixes reverses affixedNames, returning the host name registry record name is generated for with the affixes
+func (m *Matcher) stripAffixes(zone *Zone, name Hostname, prefix string, suffix string) (Hostname, bool) {
+	if zone != nil {
+		if label := strings.Trim(prefix+suffix, "."); label != "" && name == NewHostname(label+"."+string(zone.Name)) {
+			return zone.Name, true
+		}
+	}
+	remainder, ok := strings.CutPrefix(string(name), prefix)
+	if !ok {
+		return "", false
+	}
+	// first label of the host has no dots, suffix follows it
+	for i := 1; i <= len(remainder) && remainder[i-1] != '.'; i++ {
+		label, rest := remainder[:i], remainder[i:]
+		if m.WildcardReplacement != "" && label == m.WildcardReplacement {
+			label = wildcardLabel
+		}
+		if rest == suffix {
+			return NewHostname(label), true
+		}
+		if parent, ok := strings.CutPrefix(rest, suffix+"."); ok && parent != "" {
+			return NewHostname(label + "." + parent), true
+		}
+	}
+	return "", false
+}
+
+// affixName wraps first label of the name with prefix and suffix. Affixes containing dots create extra labels
+func affixName(name string, prefix string, suffix string) string {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) < 2 {
+		return prefix + parts[0] + suffix
+	}
+	return prefix + parts[0] + suffix + "." + parts[1]
+}
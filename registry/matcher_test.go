@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatcher_RegistryNames_Matching(t *testing.T) {
+	zone := NewZone("dummy.host")
+	tests := []struct {
+		name    string
+		matcher *Matcher
+		record  *Record
+		host    *Host
+		want    bool
+	}{
+		{
+			name:    "Managing exact host",
+			matcher: NewMatcher("", "", ""),
+			record:  &Record{Name: "webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing with registry prefix",
+			matcher: NewMatcher("some-prefix-", "", ""),
+			record:  &Record{Name: "some-prefix-webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing with registry suffix",
+			matcher: NewMatcher("", "-registry", ""),
+			record:  &Record{Name: "webserver-registry.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing with prefix and suffix",
+			matcher: NewMatcher("edns-", "-registry", ""),
+			record:  &Record{Name: "edns-webserver-registry.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing with dotted prefix",
+			matcher: NewMatcher("_extdns.", "", ""),
+			record:  &Record{Name: "_extdns.webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing with dotted suffix",
+			matcher: NewMatcher("", ".registry", ""),
+			record:  &Record{Name: "webserver.registry.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    true,
+		},
+		{
+			name:    "Managing new format with record type",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-cname-webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host", RecordType: "CNAME"},
+			want:    true,
+		},
+		{
+			name:    "Managing new format with record type template",
+			matcher: NewMatcher("edns-%{record_type}.", "", ""),
+			record:  &Record{Name: "edns-a.webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing legacy format with record type template",
+			matcher: NewMatcher("edns-%{record_type}.", "", ""),
+			record:  &Record{Name: "edns-.webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing apex with prefix label",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-.dummy.host"},
+			host:    &Host{Name: "dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing apex with prefixed zone",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-dummy.host"},
+			host:    &Host{Name: "dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing apex with dotted prefix",
+			matcher: NewMatcher("_extdns.", "", ""),
+			record:  &Record{Name: "_extdns.dummy.host"},
+			host:    &Host{Name: "dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing apex new format",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-a-.dummy.host"},
+			host:    &Host{Name: "dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing apex without affixes",
+			matcher: NewMatcher("", "", ""),
+			record:  &Record{Name: "dummy.host"},
+			host:    &Host{Name: "dummy.host", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Managing wildcard with replacement",
+			matcher: NewMatcher("edns-", "", "wildcard"),
+			record:  &Record{Name: "edns-cname-wildcard.dummy.host"},
+			host:    &Host{Name: "*.dummy.host", RecordType: "CNAME"},
+			want:    true,
+		},
+		{
+			name:    "Managing wildcard without replacement",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-*.dummy.host"},
+			host:    &Host{Name: "*.dummy.host", RecordType: "CNAME"},
+			want:    true,
+		},
+		{
+			name:    "Managing single label host",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-localhost"},
+			host:    &Host{Name: "localhost", RecordType: "A"},
+			want:    true,
+		},
+		{
+			name:    "Not managing wildcard with raw name when replacement configured",
+			matcher: NewMatcher("edns-", "", "wildcard"),
+			record:  &Record{Name: "edns-*.dummy.host"},
+			host:    &Host{Name: "*.dummy.host", RecordType: "CNAME"},
+			want:    false,
+		},
+		{
+			name:    "Not managing subdomain with apex label",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host", RecordType: "A"},
+			want:    false,
+		},
+		{
+			name:    "Not managing empty host",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-.dummy.host"},
+			host:    &Host{Name: ""},
+			want:    false,
+		},
+		{
+			name:    "Not managing another record type",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-aaaa-webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host", RecordType: "A"},
+			want:    false,
+		},
+		{
+			name:    "Not managing unknown prefix",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "another-webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    false,
+		},
+		{
+			name:    "Not managing unknown suffix",
+			matcher: NewMatcher("edns-", "", ""),
+			record:  &Record{Name: "edns-webserver-registry.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    false,
+		},
+		{
+			name:    "Not managing different zone",
+			matcher: NewMatcher("", "-suffix", ""),
+			record:  &Record{Name: "webserver-suffix.dummy.com"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    false,
+		},
+		{
+			name:    "Not managing different domain",
+			matcher: NewMatcher("prefix-", "", ""),
+			record:  &Record{Name: "prefix-webserver.dummy.host"},
+			host:    &Host{Name: "webserver2.dummy.host"},
+			want:    false,
+		},
+		{
+			name:    "Not managing deeper level",
+			matcher: NewMatcher("prefix-", "", ""),
+			record:  &Record{Name: "4thlevel.prefix-webserver.dummy.host"},
+			host:    &Host{Name: "webserver.dummy.host"},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make(map[Hostname]bool)
+			for _, name := range tt.matcher.RegistryNames(zone, tt.host) {
+				names[name] = true
+			}
+			assert.Equalf(t, tt.want, names[tt.record.Name], "RegistryNames(%v) contains %v", tt.host, tt.record)
+		})
+	}
+}
+
+func TestMatcher_RegistryNames(t *testing.T) {
+	matcher := NewMatcher("edns-", "", "")
+	host := NewHost("webserver.dummy.host", "A", "127.0.0.1")
+	assert.Equal(t, []Hostname{"edns-webserver.dummy.host", "edns-a-webserver.dummy.host"}, matcher.RegistryNames(NewZone("dummy.host"), host))
+
+	apex := NewHost("dummy.host", "A", "127.0.0.1")
+	assert.Equal(t, []Hostname{"edns-dummy.host", "edns-.dummy.host", "edns-a-dummy.host", "edns-a-.dummy.host"}, matcher.RegistryNames(NewZone("dummy.host"), apex))
+}
+
+func TestMatcher_IsHostRecordType(t *testing.T) {
+	matcher := NewMatcher("edns-", "", "")
+	assert.True(t, matcher.IsHostRecordType("CNAME"))
+	assert.False(t, matcher.IsHostRecordType("ALIAS"), "Provider specific types are not managed by default")
+	assert.False(t, matcher.IsHostRecordType("TXT"))
+
+	matcher.RecordTypes = []string{"A", "ALIAS", "MX"}
+	assert.True(t, matcher.IsHostRecordType("ALIAS"))
+	assert.True(t, matcher.IsHostRecordType("mx"))
+	assert.False(t, matcher.IsHostRecordType("CNAME"))
+}
+
+func TestMatcher_LegacyAndRecordTypeNames(t *testing.T) {
+	matcher := NewMatcher("edns-", "", "")
+	zone := NewZone("dummy.host")
+	host := NewHost("dummy.host", "ALIAS", "webserver.dummy.host")
+
+	assert.Equal(t, []Hostname{"edns-dummy.host", "edns-.dummy.host"}, matcher.LegacyNames(zone, host))
+	assert.Equal(t, []Hostname{"edns-alias-dummy.host", "edns-alias-.dummy.host"}, matcher.RecordTypeNames(zone, host))
+	assert.Nil(t, matcher.RecordTypeNames(zone, NewHost("dummy.host", "")))
+}
+
+func TestMatcher_HostNamesAndRecordType(t *testing.T) {
+	zone := NewZone("dummy.host")
+	tests := []struct {
+		name       string
+		matcher    *Matcher
+		record     Hostname
+		hosts      []Hostname
+		recordType string
+	}{
+		{name: "Legacy", matcher: NewMatcher("edns-", "", ""), record: "edns-webserver.dummy.host", hosts: []Hostname{"webserver.dummy.host"}},
+		{name: "Record type not managed", matcher: NewMatcher("edns-", "", ""), record: "edns-mx-mail.dummy.host", hosts: []Hostname{"mx-mail.dummy.host", "mail.dummy.host"}, recordType: "MX"},
+		{name: "Apex label", matcher: NewMatcher("edns-", "", ""), record: "edns-a-.dummy.host", hosts: []Hostname{"a-.dummy.host", "dummy.host"}, recordType: "A"},
+		{name: "Suffix with template", matcher: NewMatcher("", "-%{record_type}.reg", ""), record: "api-cname.reg.dummy.host", hosts: []Hostname{"api.dummy.host"}, recordType: "CNAME"},
+		{name: "Wildcard replacement", matcher: NewMatcher("edns-", "", "star"), record: "edns-star.dummy.host", hosts: []Hostname{"*.dummy.host"}},
+		{name: "Other prefix", matcher: NewMatcher("edns-", "", ""), record: "registry-webserver.dummy.host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.hosts, tt.matcher.HostNames(zone, tt.record))
+			assert.Equal(t, tt.recordType, tt.matcher.RecordType(zone, tt.record))
+		})
+	}
+}
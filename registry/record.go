@@ -2,63 +2,126 @@ package registry
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
 const ExternalDnsIdentifier = "heritage=external-dns"
-const OwnerId = "external-dns/owner="
-const ResourceId = "external-dns/resource="
-
-var Prefix string
+const LabelPrefix = "external-dns/"
+const OwnerId = LabelPrefix + "owner="
+const ResourceId = LabelPrefix + "resource="
 
 type Record struct {
-	Name     string
+	Name     Hostname
 	Owner    string
 	Resource string
+	// ID identifies record in the registry backend when name is not unique
+	ID string
+	// RecordType of the managed host when registry tracks it
+	RecordType string
+	// Encrypted records are stored in the registry encrypted with AES key
+	Encrypted bool
+	// Compressed records are gzipped before encryption
+	Compressed bool
+	// Labels are extra registry labels besides owner and resource, preserved on updates
+	Labels map[string]string
 }
 
 func (r Record) Info() string {
 	segments := []string{ExternalDnsIdentifier, OwnerId + r.Owner, ResourceId + r.Resource}
+	keys := make([]string, 0, len(r.Labels))
+	for key := range r.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		segments = append(segments, LabelPrefix+key+"="+r.Labels[key])
+	}
 	return strings.Join(segments, ",")
 }
 
-func (r Record) IsManaging(host *Host) bool {
-	recordParts := strings.SplitN(r.Name, ".", 2)
-	hostParts := strings.SplitN(host.Name, ".", 2)
-	recordDomain := recordParts[0]
-	recordBase := recordParts[1]
-	hostDomain := hostParts[0]
-	hostBase := hostParts[1]
+// SetLabel adds extra registry label to the record
+func (r *Record) SetLabel(key string, value string) {
+	if r.Labels == nil {
+		r.Labels = make(map[string]string)
+	}
+	r.Labels[key] = value
+}
+
+// RemoveLabel removes extra registry label from the record
+func (r *Record) RemoveLabel(key string) {
+	delete(r.Labels, key)
+}
 
-	// top level domain match for both records
-	if hostBase == recordBase {
-		//same domain, check prefix
-		return recordDomain == hostDomain || recordDomain == Prefix+hostDomain
+// Content returns registry information as it should be stored in TXT record
+func (r Record) Content(encryptor *Encryptor) (string, error) {
+	if !r.Encrypted {
+		return r.Info(), nil
+	}
+	if encryptor == nil {
+		return "", fmt.Errorf("encryption key is required to update encrypted registry record %s", r.Name)
 	}
-	return false
+	return encryptor.Encrypt(r.Info(), r.Compressed)
 }
 
 func (r Record) NewRecord(ownerId string, resource string) *Record {
-	return &Record{Name: r.Name, Owner: ownerId, Resource: resource}
+	record := &Record{Name: r.Name, Owner: ownerId, Resource: resource, ID: r.ID, RecordType: r.RecordType, Encrypted: r.Encrypted, Compressed: r.Compressed}
+	for key, value := range r.Labels {
+		record.SetLabel(key, value)
+	}
+	return record
 }
 
 func (r Record) String() string {
+	if r.RecordType != "" {
+		return fmt.Sprintf("RegistryRecord[Host:%s][Type:%s][Owner:%s][Resource:%s]", r.Name, r.RecordType, r.Owner, r.Resource)
+	}
 	return fmt.Sprintf("RegistryRecord[Host:%s][Owner:%s][Resource:%s]", r.Name, r.Owner, r.Resource)
 }
 
 func NewRecord(name string, info string) *Record {
-	owner, resource := parseInfo(info)
-	return &Record{Name: name, Owner: owner, Resource: resource}
+	owner, resource, labels := parseInfo(info)
+	return &Record{Name: NewHostname(name), Owner: owner, Resource: resource, Labels: labels}
+}
+
+// ParseRecord creates registry record from TXT record content, decrypting it when encryptor provided.
+// Returns nil if content is not External DNS registry information
+func ParseRecord(name string, content string, encryptor *Encryptor) *Record {
+	info := strings.Trim(content, "\"")
+	if strings.HasPrefix(info, ExternalDnsIdentifier) {
+		return NewRecord(name, info)
+	}
+	if encryptor == nil {
+		return nil
+	}
+
+	decrypted, compressed, err := encryptor.Decrypt(info)
+	if err != nil || !strings.HasPrefix(decrypted, ExternalDnsIdentifier) {
+		return nil
+	}
+	record := NewRecord(name, decrypted)
+	record.Encrypted = true
+	record.Compressed = compressed
+	return record
 }
 
-func parseInfo(info string) (string, string) {
+// parseInfo returns owner, resource and extra labels, labels are nil when record has none
+func parseInfo(info string) (string, string, map[string]string) {
 	owner, resource := "", ""
+	var labels map[string]string
 	for _, segment := range strings.Split(info, ",") {
 		if strings.HasPrefix(segment, OwnerId) {
 			owner = strings.TrimPrefix(segment, OwnerId)
 		} else if strings.HasPrefix(segment, ResourceId) {
 			resource = strings.TrimPrefix(segment, ResourceId)
+		} else if strings.HasPrefix(segment, LabelPrefix) {
+			if key, value, ok := strings.Cut(strings.TrimPrefix(segment, LabelPrefix), "="); ok {
+				if labels == nil {
+					labels = make(map[string]string)
+				}
+				labels[key] = value
+			}
 		}
 	}
-	return owner, resource
+	return owner, resource, labels
 }
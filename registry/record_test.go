@@ -28,53 +28,88 @@ func TestRecord_Info(t *testing.T) {
 	assert.Equal(t, want, get, "Should correctly serialize registry information")
 }
 
-func TestRecord_IsManaging(t *testing.T) {
+func TestRecord_Labels(t *testing.T) {
+	info := "heritage=external-dns,external-dns/owner=parking,external-dns/resource=ingress/test/webserver,external-dns/frozen-owner=matic,external-dns/a=b"
+	record := NewRecord("k8s_api.dummy.zone", info)
+	assert.Equal(t, map[string]string{"frozen-owner": "matic", "a": "b"}, record.Labels)
+	assert.Equal(t, "heritage=external-dns,external-dns/owner=parking,external-dns/resource=ingress/test/webserver,external-dns/a=b,external-dns/frozen-owner=matic", record.Info(), "Labels preserved in sorted order")
+
+	updated := record.NewRecord("matic", record.Resource)
+	updated.RemoveLabel("frozen-owner")
+	assert.Equal(t, "heritage=external-dns,external-dns/owner=matic,external-dns/resource=ingress/test/webserver,external-dns/a=b", updated.Info())
+	assert.Equal(t, "matic", record.Labels["frozen-owner"], "Labels of original record are not changed")
+}
+
+func TestParseRecord(t *testing.T) {
+	encryptor, _ := NewEncryptor(testEncryptionKey)
+	info := "heritage=external-dns,external-dns/owner=matic,external-dns/resource=ingress/test/webserver"
+	encrypted, _ := encryptor.Encrypt(info, true)
+
 	tests := []struct {
-		name   string
-		record Record
-		host   *Host
-		want   bool
+		name      string
+		content   string
+		encryptor *Encryptor
+		want      *Record
 	}{
 		{
-			name:   "Managing exact host",
-			record: Record{Name: "webserver.dummy.host"},
-			host:   &Host{Name: "webserver.dummy.host"},
-			want:   true,
+			name:    "Plain record",
+			content: info,
+			want:    &Record{Name: "k8s_api.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver"},
 		},
 		{
-			name:   "Managing with registry prefix",
-			record: Record{Name: "some-prefix-webserver.dummy.host"},
-			host:   &Host{Name: "webserver.dummy.host"},
-			want:   true,
+			name:    "Quoted record",
+			content: "\"" + info + "\"",
+			want:    &Record{Name: "k8s_api.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver"},
 		},
 		{
-			name:   "Managing with registry suffix",
-			record: Record{Name: "webserver-registry.dummy.host"},
-			host:   &Host{Name: "webserver.dummy.host"},
-			want:   true,
+			name:    "Not registry record",
+			content: "v=spf1 -all",
 		},
 		{
-			name:   "Not managing different zone",
-			record: Record{Name: "webserver-suffix.dummy.com"},
-			host:   &Host{Name: "webserver.dummy.host"},
-			want:   false,
+			name:    "Encrypted record without key",
+			content: encrypted,
 		},
 		{
-			name:   "Not managing different domain",
-			record: Record{Name: "prefix-webserver.dummy.host"},
-			host:   &Host{Name: "webserver2.dummy.host"},
-			want:   false,
+			name:      "Encrypted record",
+			content:   encrypted,
+			encryptor: encryptor,
+			want:      &Record{Name: "k8s_api.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver", Encrypted: true, Compressed: true},
 		},
 		{
-			name:   "Not managing deeper level",
-			record: Record{Name: "4thlevel.prefix-webserver.dummy.host"},
-			host:   &Host{Name: "webserver.dummy.host"},
-			want:   false,
+			name:      "Plain record with key",
+			content:   info,
+			encryptor: encryptor,
+			want:      &Record{Name: "k8s_api.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equalf(t, tt.want, tt.record.IsManaging(tt.host), "IsManaging(%v)", tt.host)
+			assert.Equal(t, tt.want, ParseRecord("k8s_api.dummy.zone", tt.content, tt.encryptor))
 		})
 	}
 }
+
+func TestRecord_Content(t *testing.T) {
+	encryptor, _ := NewEncryptor(testEncryptionKey)
+	record := Record{Name: "k8s_api.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver", Encrypted: true}
+
+	content, err := record.Content(encryptor)
+	assert.NoError(t, err)
+	decrypted, _, _ := encryptor.Decrypt(content)
+	assert.Equal(t, record.Info(), decrypted, "Should encrypt registry information")
+
+	_, err = record.Content(nil)
+	assert.Error(t, err, "Should require key for encrypted record")
+
+	record.Encrypted = false
+	content, err = record.Content(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, record.Info(), content, "Should keep plain registry information")
+}
+
+func TestRecord_String(t *testing.T) {
+	record := Record{Name: "edns-a-webserver.dummy.zone", Owner: "matic", Resource: "ingress/test/webserver"}
+	assert.Equal(t, "RegistryRecord[Host:edns-a-webserver.dummy.zone][Owner:matic][Resource:ingress/test/webserver]", record.String())
+	record.RecordType = "A"
+	assert.Equal(t, "RegistryRecord[Host:edns-a-webserver.dummy.zone][Type:A][Owner:matic][Resource:ingress/test/webserver]", record.String())
+}
@@ -5,21 +5,20 @@ import "strings"
 const RegistryRecordType = "TXT"
 
 type Zone struct {
-	Name  string
+	Name  Hostname
 	Hosts []*Host
+	// RegistryRecords are all registry records found in the zone, including records without hosts
+	RegistryRecords []*Record
+	// names are DNS names of the zone with records of any type, including types that are not managed
+	names map[Hostname]bool
+	// hostIndex groups record sets of the same host name
+	hostIndex map[Hostname][]*Host
+	// indexedHosts is number of hosts in the index, index is rebuilt when hosts were appended directly
+	indexedHosts int
 }
 
 func NewZone(name string) *Zone {
-	return &Zone{Name: name, Hosts: make([]*Host, 0)}
-}
-
-func (z *Zone) IsHostRecordType(recordType string) bool {
-	switch recordType {
-	case "A", "AAAA", "CNAME":
-		return true
-	default:
-		return false
-	}
+	return &Zone{Name: NewHostname(name), Hosts: make([]*Host, 0), RegistryRecords: make([]*Record, 0)}
 }
 
 func (z *Zone) IsRegistryRecordType(recordType string) bool {
@@ -28,8 +27,103 @@ func (z *Zone) IsRegistryRecordType(recordType string) bool {
 
 func (z *Zone) AddHost(record *Host) {
 	z.Hosts = append(z.Hosts, record)
+	if z.hostIndex != nil && z.indexedHosts == len(z.Hosts)-1 {
+		z.hostIndex[record.Name] = append(z.hostIndex[record.Name], record)
+		z.indexedHosts++
+	}
+}
+
+// IndexHosts builds index of hosts by name. Index is built lazily by FindHosts, call IndexHosts
+// before looking up hosts from several goroutines
+func (z *Zone) IndexHosts() {
+	z.hostIndex = make(map[Hostname][]*Host, len(z.Hosts))
+	for _, host := range z.Hosts {
+		z.hostIndex[host.Name] = append(z.hostIndex[host.Name], host)
+	}
+	z.indexedHosts = len(z.Hosts)
+}
+
+// FindHosts returns record sets of the host name in the order they were added
+func (z *Zone) FindHosts(name Hostname) []*Host {
+	if z.hostIndex == nil || z.indexedHosts != len(z.Hosts) {
+		z.IndexHosts()
+	}
+	return z.hostIndex[name]
+}
+
+func (z *Zone) AddRegistryRecord(record *Record) {
+	z.RegistryRecords = append(z.RegistryRecords, record)
+}
+
+// AddName registers DNS name of the zone that has records of a type not managed as hosts
+func (z *Zone) AddName(name Hostname) {
+	if z.names == nil {
+		z.names = make(map[Hostname]bool)
+	}
+	z.names[name] = true
 }
 
-func (z *Zone) IsManagingEndpoint(endpoint *Endpoint) bool {
-	return strings.HasSuffix(endpoint.Host, z.Name)
+// OrphanRecords returns registry records of the zone that do not belong to any host and whose host name
+// has no DNS records of any type. Registry record names are resolved to host names for every known record type,
+// so records of hosts with types that are not managed are not orphans
+func (z *Zone) OrphanRecords(matcher *Matcher) []*Record {
+	attached := make(map[*Record]bool)
+	names := make(map[Hostname]bool, len(z.names)+len(z.Hosts))
+	for name := range z.names {
+		names[name] = true
+	}
+	for _, host := range z.Hosts {
+		names[host.Name] = true
+		for _, record := range host.RegistryRecords {
+			attached[record] = true
+		}
+	}
+	orphans := make([]*Record, 0)
+	for _, record := range z.RegistryRecords {
+		// Records of registries outside of DNS are named after their hosts
+		if attached[record] || names[record.Name] || containsName(names, matcher.HostNames(z, record.Name)) {
+			continue
+		}
+		orphans = append(orphans, record)
+	}
+	return orphans
+}
+
+// UniqueRecords returns registry records without repeats in their original order. Legacy registry record
+// is shared between record sets of the same host, so it is collected once for every record set
+func UniqueRecords(records []*Record) []*Record {
+	seen := make(map[*Record]bool, len(records))
+	unique := make([]*Record, 0, len(records))
+	for _, record := range records {
+		if !seen[record] {
+			seen[record] = true
+			unique = append(unique, record)
+		}
+	}
+	return unique
+}
+
+func containsName(names map[Hostname]bool, candidates []Hostname) bool {
+	for _, candidate := range candidates {
+		if names[candidate] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsManagingName checks whether name is the zone apex or belongs to the zone on a label boundary
+func (z *Zone) IsManagingName(name Hostname) bool {
+	return name == z.Name || strings.HasSuffix(string(name), "."+string(z.Name))
+}
+
+// FindZone returns the most specific zone managing the name, so delegated subzones win over parent zones
+func FindZone(zones []*Zone, name Hostname) *Zone {
+	var found *Zone
+	for _, zone := range zones {
+		if zone.IsManagingName(name) && (found == nil || len(zone.Name) > len(found.Name)) {
+			found = zone
+		}
+	}
+	return found
 }
@@ -5,47 +5,116 @@ import (
 	"testing"
 )
 
-func TestZone_IsManagingEndpoint(t *testing.T) {
+func TestZone_IsManagingName(t *testing.T) {
 	zone := NewZone("dummy.host")
 	tests := []struct {
-		name     string
-		endpoint Endpoint
-		want     bool
+		name string
+		host Hostname
+		want bool
 	}{
 		{
-			name:     "Apex Endpoint",
-			endpoint: Endpoint{Host: "dummy.host"},
-			want:     true,
+			name: "Apex",
+			host: "dummy.host",
+			want: true,
 		},
 		{
-			name:     "Subdomain",
-			endpoint: Endpoint{Host: "test.dummy.host"},
-			want:     true,
+			name: "Subdomain",
+			host: "test.dummy.host",
+			want: true,
 		},
 		{
-			name:     "Deep level domain",
-			endpoint: Endpoint{Host: "webserver.test.dummy.host"},
-			want:     true,
+			name: "Deep level domain",
+			host: "webserver.test.dummy.host",
+			want: true,
 		},
 		{
-			name:     "Another top level",
-			endpoint: Endpoint{Host: "webserver.dummy.com"},
-			want:     false,
+			name: "Another top level",
+			host: "webserver.dummy.com",
+			want: false,
 		},
 		{
-			name:     "Another 2nd level",
-			endpoint: Endpoint{Host: "webserver.example.host"},
-			want:     false,
+			name: "Another 2nd level",
+			host: "webserver.example.host",
+			want: false,
 		},
 		{
-			name:     "Contain zone name",
-			endpoint: Endpoint{Host: "dummy.host.example.com"},
-			want:     false,
+			name: "Zone name without label boundary",
+			host: "notdummy.host",
+			want: false,
+		},
+		{
+			name: "Subdomain without label boundary",
+			host: "api.notdummy.host",
+			want: false,
+		},
+		{
+			name: "Contain zone name",
+			host: "dummy.host.example.com",
+			want: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equalf(t, tt.want, zone.IsManagingEndpoint(&tt.endpoint), "IsManagingEndpoint(%v)", tt.endpoint)
+			assert.Equalf(t, tt.want, zone.IsManagingName(tt.host), "IsManagingName(%v)", tt.host)
 		})
 	}
 }
+
+func TestFindZone(t *testing.T) {
+	parent := NewZone("dummy.host")
+	subzone := NewZone("eu.dummy.host")
+	zones := []*Zone{parent, subzone}
+
+	assert.Equal(t, parent, FindZone(zones, "api.dummy.host"))
+	assert.Equal(t, parent, FindZone(zones, "dummy.host"))
+	assert.Equal(t, subzone, FindZone(zones, "api.eu.dummy.host"), "Subzone preferred")
+	assert.Equal(t, subzone, FindZone([]*Zone{subzone, parent}, "eu.dummy.host"), "Subzone preferred regardless of order")
+	assert.Equal(t, parent, FindZone(zones, "api.noteu.dummy.host"))
+	assert.Nil(t, FindZone(zones, "api.notdummy.host"))
+}
+
+func TestZone_OrphanRecords(t *testing.T) {
+	zone := NewZone("dummy.host")
+	attached := &Record{Name: "edns-webserver.dummy.host", Owner: "cluster-1"}
+	orphan := &Record{Name: "edns-deleted.dummy.host", Owner: "cluster-1"}
+	host := NewHost("webserver.dummy.host", "A", "127.0.0.1")
+	host.AddRegistryRecord(attached)
+	zone.AddHost(host)
+	zone.AddRegistryRecord(attached)
+	zone.AddRegistryRecord(orphan)
+
+	assert.Equal(t, []*Record{orphan}, zone.OrphanRecords(NewMatcher("edns-", "", "")))
+}
+
+func TestZone_OrphanRecords_UnmanagedRecordTypes(t *testing.T) {
+	zone := NewZone("dummy.host")
+	zone.AddName("mail.dummy.host")
+	zone.AddName("dummy.host")
+	mxRecord := &Record{Name: "edns-mx-mail.dummy.host", Owner: "cluster-1"}
+	legacyRecord := &Record{Name: "edns-mail.dummy.host", Owner: "cluster-1"}
+	apexRecord := &Record{Name: "edns-ns-dummy.host", Owner: "cluster-1"}
+	itemRecord := &Record{Name: "mail.dummy.host", RecordType: "MX", Owner: "cluster-1"}
+	orphan := &Record{Name: "edns-mx-deleted.dummy.host", Owner: "cluster-1"}
+	for _, record := range []*Record{mxRecord, legacyRecord, apexRecord, itemRecord, orphan} {
+		zone.AddRegistryRecord(record)
+	}
+
+	assert.Equal(t, []*Record{orphan}, zone.OrphanRecords(NewMatcher("edns-", "", "")), "Records of names with any DNS records are not orphans")
+}
+
+func TestZone_FindHosts(t *testing.T) {
+	zone := NewZone("dummy.host")
+	a := NewHost("webserver.dummy.host", "A", "127.0.0.1")
+	aaaa := NewHost("webserver.dummy.host", "AAAA", "::1")
+	zone.AddHost(a)
+	zone.AddHost(NewHost("api.dummy.host", "A", "127.0.0.2"))
+
+	assert.Equal(t, []*Host{a}, zone.FindHosts("webserver.dummy.host"))
+	zone.AddHost(aaaa)
+	assert.Equal(t, []*Host{a, aaaa}, zone.FindHosts("webserver.dummy.host"), "Index updated by AddHost")
+	assert.Nil(t, zone.FindHosts("mail.dummy.host"))
+
+	mail := NewHost("mail.dummy.host", "MX", "mail.example.com")
+	zone.Hosts = append(zone.Hosts, mail)
+	assert.Equal(t, []*Host{mail}, zone.FindHosts("mail.dummy.host"), "Index rebuilt for hosts appended directly")
+}
@@ -14,6 +14,9 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+// IngressSourceName is the name of the source for Ingress objects
+const IngressSourceName = "ingress"
+
 type ingressSource struct {
 	client          kubernetes.Interface
 	namespace       string
@@ -102,13 +105,13 @@ func endpointsFromIngress(ing *networkv1.Ingress) []*registry.Endpoint {
 		if rule.Host == "" {
 			continue
 		}
-		definedHostsEndpoints = append(definedHostsEndpoints, registry.NewEndpoint(rule.Host, resource))
+		definedHostsEndpoints = append(definedHostsEndpoints, registry.NewEndpoint(rule.Host, resource, IngressSourceName))
 	}
 
 	// Gather endpoints defined on annotations in the ingress
 	var annotationEndpoints []*registry.Endpoint
 	for _, hostname := range getHostnamesFromAnnotations(ing.Annotations) {
-		annotationEndpoints = append(annotationEndpoints, registry.NewEndpoint(hostname, resource))
+		annotationEndpoints = append(annotationEndpoints, registry.NewEndpoint(hostname, resource, IngressSourceName))
 	}
 
 	// Include endpoints according to the hostname source annotation in our final list
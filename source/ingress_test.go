@@ -90,6 +90,33 @@ func testIngressEndpoints(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Canonical hosts",
+			ingressItems: []fakeIngress{
+				{
+					name:      "fake1",
+					namespace: namespace,
+					dnsnames:  []string{"Fake1.Dummy.Host", "bücher.dummy.host"},
+					annotations: map[string]string{
+						hostnameAnnotationKey: "fake2.dummy.host.",
+					},
+				},
+			},
+			expectedEndpoints: []*registry.Endpoint{
+				{
+					Host:     "fake1.dummy.host",
+					Resource: "ingress/testing/fake1",
+				},
+				{
+					Host:     "xn--bcher-kva.dummy.host",
+					Resource: "ingress/testing/fake1",
+				},
+				{
+					Host:     "fake2.dummy.host",
+					Resource: "ingress/testing/fake1",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -23,6 +23,9 @@ import (
 // IstioMeshGateway is the built in gateway for all sidecars
 const IstioMeshGateway = "mesh"
 
+// VirtualServiceSourceName is the name of the source for Istio VirtualService objects
+const VirtualServiceSourceName = "istio-virtualservice"
+
 // virtualServiceSource is an implementation of Source for Istio VirtualService objects.
 // The implementation uses the spec.hosts values for the hostnames.
 // Use targetAnnotationKey to explicitly set Endpoint.
@@ -208,7 +211,7 @@ func (sc *virtualServiceSource) endpointsFromVirtualService(ctx context.Context,
 			continue
 		}
 
-		endpoints = append(endpoints, registry.NewEndpoint(host, resource))
+		endpoints = append(endpoints, registry.NewEndpoint(host, resource, VirtualServiceSourceName))
 	}
 
 	return endpoints, nil
@@ -65,4 +65,8 @@ func validateEndpoint(t *testing.T, endpoint, expected *registry.Endpoint) {
 	if endpoint.Resource != expected.Resource {
 		t.Errorf("Resource expected %v, got %v", expected.Resource, endpoint.Resource)
 	}
+
+	if expected.Source != "" && endpoint.Source != expected.Source {
+		t.Errorf("Source expected %v, got %v", expected.Source, endpoint.Source)
+	}
 }
@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 // ErrSourceNotFound is returned when a requested source doesn't exist.
@@ -44,6 +45,7 @@ type ClientGenerator interface {
 // will be generated
 type SingletonClientGenerator struct {
 	KubeConfig     string
+	KubeContext    string
 	APIServerURL   string
 	RequestTimeout time.Duration
 	kubeClient     kubernetes.Interface
@@ -58,7 +60,7 @@ type SingletonClientGenerator struct {
 func (p *SingletonClientGenerator) KubeClient() (kubernetes.Interface, error) {
 	var err error
 	p.kubeOnce.Do(func() {
-		p.kubeClient, err = NewKubeClient(p.KubeConfig, p.APIServerURL, p.RequestTimeout)
+		p.kubeClient, err = NewKubeClient(p.KubeConfig, p.KubeContext, p.APIServerURL, p.RequestTimeout)
 	})
 	return p.kubeClient, err
 }
@@ -67,7 +69,7 @@ func (p *SingletonClientGenerator) KubeClient() (kubernetes.Interface, error) {
 func (p *SingletonClientGenerator) IstioClient() (istioclient.Interface, error) {
 	var err error
 	p.istioOnce.Do(func() {
-		p.istioClient, err = NewIstioClient(p.KubeConfig, p.APIServerURL)
+		p.istioClient, err = NewIstioClient(p.KubeConfig, p.KubeContext, p.APIServerURL)
 	})
 	return p.istioClient, err
 }
@@ -76,7 +78,7 @@ func (p *SingletonClientGenerator) IstioClient() (istioclient.Interface, error)
 func (p *SingletonClientGenerator) DynamicKubernetesClient() (dynamic.Interface, error) {
 	var err error
 	p.dynCliOnce.Do(func() {
-		p.dynKubeClient, err = NewDynamicKubernetesClient(p.KubeConfig, p.APIServerURL, p.RequestTimeout)
+		p.dynKubeClient, err = NewDynamicKubernetesClient(p.KubeConfig, p.KubeContext, p.APIServerURL, p.RequestTimeout)
 	})
 	return p.dynKubeClient, err
 }
@@ -104,7 +106,7 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 	//		return nil, err
 	//	}
 	//	return NewServiceSource(ctx, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.AlwaysPublishNotReadyAddresses, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter, cfg.ResolveLoadBalancerHostname)
-	case "ingress":
+	case IngressSourceName:
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
@@ -126,7 +128,7 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 	//		return nil, err
 	//	}
 	//	return NewIstioGatewaySource(ctx, kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
-	case "istio-virtualservice":
+	case VirtualServiceSourceName:
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
 			return nil, err
@@ -143,8 +145,8 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 	return nil, ErrSourceNotFound
 }
 
-func instrumentedRESTConfig(kubeConfig, apiServerURL string, requestTimeout time.Duration) (*rest.Config, error) {
-	config, err := GetRestConfig(kubeConfig, apiServerURL)
+func instrumentedRESTConfig(kubeConfig, kubeContext, apiServerURL string, requestTimeout time.Duration) (*rest.Config, error) {
+	config, err := GetRestConfig(kubeConfig, kubeContext, apiServerURL)
 	if err != nil {
 		return nil, err
 	}
@@ -161,27 +163,25 @@ func instrumentedRESTConfig(kubeConfig, apiServerURL string, requestTimeout time
 }
 
 // GetRestConfig returns the rest clients config to get automatically
-// data if you run inside a cluster or by passing flags.
-func GetRestConfig(kubeConfig, apiServerURL string) (*rest.Config, error) {
-	if kubeConfig == "" {
-		if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
-			kubeConfig = clientcmd.RecommendedHomeFile
-		}
+// data if you run inside a cluster or by passing flags. Empty kubeContext selects
+// the current context of the kubeConfig.
+func GetRestConfig(kubeConfig, kubeContext, apiServerURL string) (*rest.Config, error) {
+	kubeConfig, err := resolveKubeConfig(kubeConfig, kubeContext)
+	if err != nil {
+		return nil, err
 	}
 	log.Debugf("apiServerURL: %s", apiServerURL)
 	log.Debugf("kubeConfig: %s", kubeConfig)
+	log.Debugf("kubeContext: %s", kubeContext)
 
 	// evaluate whether to use kubeConfig-file or serviceaccount-token
-	var (
-		config *rest.Config
-		err    error
-	)
+	var config *rest.Config
 	if kubeConfig == "" {
 		log.Infof("Using inCluster-config based on serviceaccount-token")
 		config, err = rest.InClusterConfig()
 	} else {
 		log.Infof("Using kubeConfig")
-		config, err = clientcmd.BuildConfigFromFlags(apiServerURL, kubeConfig)
+		config, err = buildConfigFromKubeConfig(kubeConfig, kubeContext, apiServerURL)
 	}
 	if err != nil {
 		return nil, err
@@ -190,12 +190,37 @@ func GetRestConfig(kubeConfig, apiServerURL string) (*rest.Config, error) {
 	return config, nil
 }
 
+// resolveKubeConfig defaults kubeConfig to the recommended home file when it exists. Context can only be selected
+// from a kubeConfig file, so in-cluster config is not used in place of the requested context
+func resolveKubeConfig(kubeConfig, kubeContext string) (string, error) {
+	if kubeConfig == "" {
+		if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+			kubeConfig = clientcmd.RecommendedHomeFile
+		}
+	}
+	if kubeConfig == "" && kubeContext != "" {
+		return "", errors.Errorf("kubeconfig file is required to use context '%s', none found at %s", kubeContext, clientcmd.RecommendedHomeFile)
+	}
+	return kubeConfig, nil
+}
+
+// buildConfigFromKubeConfig loads kubeConfig file using kubeContext instead of the current context when set
+func buildConfigFromKubeConfig(kubeConfig, kubeContext, apiServerURL string) (*rest.Config, error) {
+	if kubeContext == "" {
+		return clientcmd.BuildConfigFromFlags(apiServerURL, kubeConfig)
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext, ClusterInfo: clientcmdapi.Cluster{Server: apiServerURL}},
+	).ClientConfig()
+}
+
 // NewKubeClient returns a new Kubernetes client object. It takes a Config and
 // uses APIServerURL and KubeConfig attributes to connect to the cluster. If
 // KubeConfig isn't provided it defaults to using the recommended default.
-func NewKubeClient(kubeConfig, apiServerURL string, requestTimeout time.Duration) (*kubernetes.Clientset, error) {
+func NewKubeClient(kubeConfig, kubeContext, apiServerURL string, requestTimeout time.Duration) (*kubernetes.Clientset, error) {
 	log.Infof("Instantiating new Kubernetes client")
-	config, err := instrumentedRESTConfig(kubeConfig, apiServerURL, requestTimeout)
+	config, err := instrumentedRESTConfig(kubeConfig, kubeContext, apiServerURL, requestTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -215,14 +240,13 @@ func NewKubeClient(kubeConfig, apiServerURL string, requestTimeout time.Duration
 // wrappers) to the client's config at this level. Furthermore, the Istio client
 // constructor does not expose the ability to override the Kubernetes API server endpoint,
 // so the apiServerURL config attribute has no effect.
-func NewIstioClient(kubeConfig string, apiServerURL string) (*istioclient.Clientset, error) {
-	if kubeConfig == "" {
-		if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
-			kubeConfig = clientcmd.RecommendedHomeFile
-		}
+func NewIstioClient(kubeConfig, kubeContext, apiServerURL string) (*istioclient.Clientset, error) {
+	kubeConfig, err := resolveKubeConfig(kubeConfig, kubeContext)
+	if err != nil {
+		return nil, err
 	}
 
-	restCfg, err := clientcmd.BuildConfigFromFlags(apiServerURL, kubeConfig)
+	restCfg, err := buildConfigFromKubeConfig(kubeConfig, kubeContext, apiServerURL)
 	if err != nil {
 		return nil, err
 	}
@@ -238,8 +262,8 @@ func NewIstioClient(kubeConfig string, apiServerURL string) (*istioclient.Client
 // NewDynamicKubernetesClient returns a new Dynamic Kubernetes client object. It takes a Config and
 // uses APIServerURL and KubeConfig attributes to connect to the cluster. If
 // KubeConfig isn't provided it defaults to using the recommended default.
-func NewDynamicKubernetesClient(kubeConfig, apiServerURL string, requestTimeout time.Duration) (dynamic.Interface, error) {
-	config, err := instrumentedRESTConfig(kubeConfig, apiServerURL, requestTimeout)
+func NewDynamicKubernetesClient(kubeConfig, kubeContext, apiServerURL string, requestTimeout time.Duration) (dynamic.Interface, error) {
+	config, err := instrumentedRESTConfig(kubeConfig, kubeContext, apiServerURL, requestTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,64 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+current-context: old
+clusters:
+- name: old
+  cluster:
+    server: https://old.cluster:6443
+- name: new
+  cluster:
+    server: https://new.cluster:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: old
+  context:
+    cluster: old
+    user: admin
+- name: new
+  context:
+    cluster: new
+    user: admin
+`
+
+func TestGetRestConfig_Context(t *testing.T) {
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfig, []byte(testKubeConfig), 0o600))
+
+	config, err := GetRestConfig(kubeConfig, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://old.cluster:6443", config.Host, "Current context used by default")
+
+	config, err = GetRestConfig(kubeConfig, "new", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://new.cluster:6443", config.Host)
+
+	_, err = GetRestConfig(kubeConfig, "missing", "")
+	assert.Error(t, err)
+}
+
+func TestGetRestConfig_ContextWithoutKubeConfig(t *testing.T) {
+	if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+		t.Skipf("%s exists", clientcmd.RecommendedHomeFile)
+	}
+
+	_, err := GetRestConfig("", "new", "")
+	assert.ErrorContains(t, err, "kubeconfig file is required to use context 'new'", "In-cluster config not used in place of the context")
+
+	_, err = NewIstioClient("", "new", "")
+	assert.ErrorContains(t, err, "kubeconfig file is required to use context 'new'")
+}